@@ -19,10 +19,11 @@ var randomAlbumCmd = &cobra.Command{
 		albumName, _ := cmd.Flags().GetString("album")
 		outputPath, _ := cmd.Flags().GetString("output")
 		fileType, _ := cmd.Flags().GetString("type")
+		preview, _ := cmd.Flags().GetBool("preview")
 		if albumName == "" {
 			return fmt.Errorf("album name is required, use --album")
 		}
-		return ctrl.DownloadRandomFromAlbum(albumName, outputPath, fileType)
+		return ctrl.DownloadRandomFromAlbum(albumName, outputPath, fileType, preview)
 	},
 }
 
@@ -41,10 +42,39 @@ Example:
 		outputPath, _ := cmd.Flags().GetString("output")
 		fileType, _ := cmd.Flags().GetString("type")
 		password, _ := cmd.Flags().GetString("password")
+		preview, _ := cmd.Flags().GetBool("preview")
 		if albumURL == "" {
 			return fmt.Errorf("album URL is required, use --url")
 		}
-		return ctrl.DownloadRandomFromPublicAlbum(albumURL, outputPath, fileType, password)
+		return ctrl.DownloadRandomFromPublicAlbum(albumURL, outputPath, fileType, password, preview)
+	},
+}
+
+var mirrorLinkCmd = &cobra.Command{
+	Use:   "mirror-link",
+	Short: "Mirror an entire public album link to a local directory (no login required)",
+	Long: `Download every file from a public album using its share link, keeping a
+local directory in sync across repeated runs.
+
+A re-run only fetches files added or changed since the last mirror, skips
+files whose content hasn't changed, resumes any partially-downloaded file,
+and with --prune also removes local files that were removed from the album.
+
+Example:
+  ente album mirror-link --url "https://albums.ente.io/?t=TOKEN#KEY" --output ./backup
+  ente album mirror-link --url "https://albums.ente.io/?t=TOKEN#KEY" --output ./backup --prune`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		albumURL, _ := cmd.Flags().GetString("url")
+		outputPath, _ := cmd.Flags().GetString("output")
+		fileType, _ := cmd.Flags().GetString("type")
+		password, _ := cmd.Flags().GetString("password")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		prune, _ := cmd.Flags().GetBool("prune")
+		if albumURL == "" {
+			return fmt.Errorf("album URL is required, use --url")
+		}
+		return ctrl.DownloadAllFromPublicAlbum(albumURL, outputPath, fileType, password, concurrency, prune)
 	},
 }
 
@@ -55,6 +85,7 @@ func init() {
 	randomAlbumCmd.Flags().StringP("album", "a", "", "Album name (required)")
 	randomAlbumCmd.Flags().StringP("output", "o", ".", "Output path (file or directory)")
 	randomAlbumCmd.Flags().StringP("type", "t", "", "Filter: image|video|live or extension like .jpg, .png, .mp4")
+	randomAlbumCmd.Flags().Bool("preview", false, "Render a blurhash preview and confirm before downloading")
 	_ = randomAlbumCmd.MarkFlagRequired("album")
 
 	// random-link subcommand (public albums, no login)
@@ -62,8 +93,19 @@ func init() {
 	randomLinkCmd.Flags().StringP("output", "o", ".", "Output path (file or directory)")
 	randomLinkCmd.Flags().StringP("type", "t", "", "Filter: image|video|live or extension like .jpg, .png, .mp4")
 	randomLinkCmd.Flags().StringP("password", "p", "", "Password for protected albums")
+	randomLinkCmd.Flags().Bool("preview", false, "Render a blurhash preview and confirm before downloading")
 	_ = randomLinkCmd.MarkFlagRequired("url")
 
+	// mirror-link subcommand (public albums, no login)
+	mirrorLinkCmd.Flags().StringP("url", "u", "", "Public album URL (required)")
+	mirrorLinkCmd.Flags().StringP("output", "o", ".", "Output directory")
+	mirrorLinkCmd.Flags().StringP("type", "t", "", "Filter: image|video|live or extension like .jpg, .png, .mp4")
+	mirrorLinkCmd.Flags().StringP("password", "p", "", "Password for protected albums")
+	mirrorLinkCmd.Flags().IntP("concurrency", "c", 4, "Number of files to download in parallel")
+	mirrorLinkCmd.Flags().Bool("prune", false, "Delete local files that were removed from the album")
+	_ = mirrorLinkCmd.MarkFlagRequired("url")
+
 	albumCmd.AddCommand(randomAlbumCmd)
 	albumCmd.AddCommand(randomLinkCmd)
+	albumCmd.AddCommand(mirrorLinkCmd)
 }
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Ensu chat operations (requires login)",
+}
+
+var chatInitKeyCmd = &cobra.Command{
+	Use:   "init-key",
+	Short: "Generate and register this account's chat key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		return ctrl.InitChatKey(context.Background())
+	},
+}
+
+var chatPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Sync local chat state with the server",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		return ctrl.PullChatDiff(context.Background())
+	},
+}
+
+var chatListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List locally-synced chat sessions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		sessions, err := ctrl.ListChatSessions(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			fmt.Printf("%s\t%s\n", session.SessionUUID, session.Title)
+		}
+		return nil
+	},
+}
+
+var chatSendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send a chat message, creating a new session unless --session is given",
+	Long: `Send a chat message.
+
+Example:
+  ente chat send --message "hello" --title "First chat"
+  ente chat send --message "a follow up" --session SESSION_UUID`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		sessionUUID, _ := cmd.Flags().GetString("session")
+		title, _ := cmd.Flags().GetString("title")
+		message, _ := cmd.Flags().GetString("message")
+		if message == "" {
+			return fmt.Errorf("message body is required, use --message")
+		}
+		sentSessionUUID, err := ctrl.SendChatMessage(context.Background(), sessionUUID, title, message)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Sent to session %s\n", sentSessionUUID)
+		return nil
+	},
+}
+
+var chatDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a chat session or message",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		sessionUUID, _ := cmd.Flags().GetString("session")
+		messageUUID, _ := cmd.Flags().GetString("message-id")
+		if sessionUUID == "" && messageUUID == "" {
+			return fmt.Errorf("one of --session or --message-id is required")
+		}
+		if sessionUUID != "" {
+			return ctrl.DeleteChatSession(context.Background(), sessionUUID)
+		}
+		return ctrl.DeleteChatMessage(context.Background(), messageUUID)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+
+	chatSendCmd.Flags().StringP("session", "s", "", "Existing session UUID to reply in (creates a new session if omitted)")
+	chatSendCmd.Flags().String("title", "", "Title for a newly created session")
+	chatSendCmd.Flags().StringP("message", "m", "", "Message body (required)")
+	_ = chatSendCmd.MarkFlagRequired("message")
+
+	chatDeleteCmd.Flags().StringP("session", "s", "", "Session UUID to delete")
+	chatDeleteCmd.Flags().String("message-id", "", "Message UUID to delete")
+
+	chatCmd.AddCommand(chatInitKeyCmd)
+	chatCmd.AddCommand(chatPullCmd)
+	chatCmd.AddCommand(chatListCmd)
+	chatCmd.AddCommand(chatSendCmd)
+	chatCmd.AddCommand(chatDeleteCmd)
+}
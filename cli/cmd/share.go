@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Manage an album's public share link",
+}
+
+var shareCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create (or refresh) an album's public share link",
+	Long: `Create a public share link for an album. The collection key is
+base58-encoded into the printed URL's fragment and never sent to the
+server; with --password, the server only receives the Argon2 parameters
+needed to later verify an unlock attempt.
+
+Example:
+  ente album share create --album "Trip"
+  ente album share create --album "Trip" --password "secret" --expires 168h
+  ente album share create --album "Trip" --device-limit 5`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		albumName, _ := cmd.Flags().GetString("album")
+		password, _ := cmd.Flags().GetString("password")
+		expires, _ := cmd.Flags().GetDuration("expires")
+		deviceLimit, _ := cmd.Flags().GetInt("device-limit")
+		if albumName == "" {
+			return fmt.Errorf("album name is required, use --album")
+		}
+		return ctrl.CreateAlbumShare(albumName, password, expires, deviceLimit)
+	},
+}
+
+var shareListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show an album's public share link",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		albumName, _ := cmd.Flags().GetString("album")
+		if albumName == "" {
+			return fmt.Errorf("album name is required, use --album")
+		}
+		return ctrl.ListAlbumShares(albumName)
+	},
+}
+
+var shareRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke an album's public share link",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recoverWithLog()
+		albumURL, _ := cmd.Flags().GetString("url")
+		if albumURL == "" {
+			return fmt.Errorf("share URL is required, use --url")
+		}
+		return ctrl.RevokeAlbumShare(albumURL)
+	},
+}
+
+func init() {
+	shareCreateCmd.Flags().StringP("album", "a", "", "Album name (required)")
+	shareCreateCmd.Flags().StringP("password", "p", "", "Password to protect the link with (optional)")
+	shareCreateCmd.Flags().Duration("expires", 0, "Link expiry, e.g. 168h (optional, defaults to never)")
+	shareCreateCmd.Flags().Int("device-limit", 0, "Max number of distinct devices that may use the link (0 = unlimited)")
+	_ = shareCreateCmd.MarkFlagRequired("album")
+
+	shareListCmd.Flags().StringP("album", "a", "", "Album name (required)")
+	_ = shareListCmd.MarkFlagRequired("album")
+
+	shareRevokeCmd.Flags().StringP("url", "u", "", "Share URL to revoke (required)")
+	_ = shareRevokeCmd.MarkFlagRequired("url")
+
+	shareCmd.AddCommand(shareCreateCmd)
+	shareCmd.AddCommand(shareListCmd)
+	shareCmd.AddCommand(shareRevokeCmd)
+	albumCmd.AddCommand(shareCmd)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"strconv"
+)
+
+// ChatKey is the caller's identity key for ensu chat, as returned by
+// UpsertChatKey/GetChatKey.
+type ChatKey struct {
+	UserID                  int64  `json:"userID"`
+	PublicKey               string `json:"publicKey"`
+	RemainingOneTimePreKeys int64  `json:"remainingOneTimePreKeys"`
+}
+
+// ChatSession is an end-to-end encrypted chat session envelope. EncryptedData
+// and Header are nil once the session has been tombstoned.
+type ChatSession struct {
+	SessionUUID   string  `json:"sessionUUID"`
+	EncryptedData *string `json:"encryptedData"`
+	Header        *string `json:"header"`
+	IsDeleted     bool    `json:"isDeleted"`
+	CreatedAt     int64   `json:"createdAt"`
+	UpdatedAt     int64   `json:"updatedAt"`
+}
+
+// ChatMessage is an end-to-end encrypted chat message envelope, scoped to a
+// session and optionally threaded off a parent message.
+type ChatMessage struct {
+	MessageUUID       string  `json:"messageUUID"`
+	SessionUUID       string  `json:"sessionUUID"`
+	ParentMessageUUID *string `json:"parentMessageUUID"`
+	EncryptedData     *string `json:"encryptedData"`
+	Header            *string `json:"header"`
+	IsDeleted         bool    `json:"isDeleted"`
+	CreatedAt         int64   `json:"createdAt"`
+	UpdatedAt         int64   `json:"updatedAt"`
+}
+
+// ChatSessionTombstone records that a session was deleted, for diff sync.
+type ChatSessionTombstone struct {
+	SessionUUID string `json:"sessionUUID"`
+	DeletedAt   int64  `json:"deletedAt"`
+}
+
+// ChatMessageTombstone records that a message was deleted, for diff sync.
+type ChatMessageTombstone struct {
+	MessageUUID string `json:"messageUUID"`
+	DeletedAt   int64  `json:"deletedAt"`
+}
+
+// ChatDiffTombstones groups the two tombstone kinds returned by a chat diff
+// page, mirroring the server's response shape.
+type ChatDiffTombstones struct {
+	Sessions []ChatSessionTombstone `json:"sessions"`
+	Messages []ChatMessageTombstone `json:"messages"`
+}
+
+// ChatDiffResponse is a page of the ensu chat diff: sessions and messages
+// upserted or deleted since SinceTime, plus Timestamp, the cursor to pass as
+// the next page's SinceTime.
+type ChatDiffResponse struct {
+	Sessions   []ChatSession      `json:"sessions"`
+	Messages   []ChatMessage      `json:"messages"`
+	Tombstones ChatDiffTombstones `json:"tombstones"`
+	Timestamp  int64              `json:"timestamp"`
+}
+
+// UpsertChatKey uploads (or rotates) the caller's ensu chat identity key.
+func (c *Client) UpsertChatKey(ctx context.Context, publicKey string) (*ChatKey, error) {
+	var res ChatKey
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetBody(map[string]string{"publicKey": publicKey}).
+		SetResult(&res).
+		Post("/ensu-chat/key")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// UpsertChatSession creates or updates an end-to-end encrypted chat session.
+func (c *Client) UpsertChatSession(ctx context.Context, sessionUUID string, encryptedData, header string) (*ChatSession, error) {
+	var res ChatSession
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetBody(map[string]string{
+			"sessionUUID":   sessionUUID,
+			"encryptedData": encryptedData,
+			"header":        header,
+		}).
+		SetResult(&res).
+		Post("/ensu-chat/session")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// UpsertChatMessage creates or updates an end-to-end encrypted chat message.
+func (c *Client) UpsertChatMessage(ctx context.Context, messageUUID, sessionUUID string, parentMessageUUID *string, encryptedData, header string) (*ChatMessage, error) {
+	body := map[string]interface{}{
+		"messageUUID":   messageUUID,
+		"sessionUUID":   sessionUUID,
+		"encryptedData": encryptedData,
+		"header":        header,
+	}
+	if parentMessageUUID != nil {
+		body["parentMessageUUID"] = *parentMessageUUID
+	}
+	var res ChatMessage
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetBody(body).
+		SetResult(&res).
+		Post("/ensu-chat/message")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// DeleteChatSession tombstones a chat session.
+func (c *Client) DeleteChatSession(ctx context.Context, sessionUUID string) (*ChatSessionTombstone, error) {
+	var res ChatSessionTombstone
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetQueryParam("id", sessionUUID).
+		SetResult(&res).
+		Delete("/ensu-chat/session")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// DeleteChatMessage tombstones a chat message.
+func (c *Client) DeleteChatMessage(ctx context.Context, messageUUID string) (*ChatMessageTombstone, error) {
+	var res ChatMessageTombstone
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetQueryParam("id", messageUUID).
+		SetResult(&res).
+		Delete("/ensu-chat/message")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// GetChatDiff fetches one page of the ensu chat diff since sinceTime,
+// bounded by limit.
+func (c *Client) GetChatDiff(ctx context.Context, sinceTime int64, limit int64) (*ChatDiffResponse, error) {
+	var res ChatDiffResponse
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetQueryParam("sinceTime", strconv.FormatInt(sinceTime, 10)).
+		SetQueryParam("limit", strconv.FormatInt(limit, 10)).
+		SetResult(&res).
+		Get("/ensu-chat/diff")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
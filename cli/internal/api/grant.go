@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"strconv"
+)
+
+// Grant is one entry in a collection's public-link ACL: the collection key
+// encrypted for a single grantee, alongside what's needed to re-derive the
+// wrapping secret.
+type Grant struct {
+	GranteeID          string  `json:"granteeID"`
+	AuthMethod         string  `json:"authMethod"`
+	EncryptedKey       string  `json:"encryptedKey"`
+	KeyDecryptionNonce string  `json:"keyDecryptionNonce"`
+	Nonce              string  `json:"nonce"`
+	OpsLimit           int64   `json:"opsLimit"`
+	MemLimit           int64   `json:"memLimit"`
+	PublicKey          *string `json:"publicKey"`
+	CreatedAt          int64   `json:"createdAt"`
+	UpdatedAt          int64   `json:"updatedAt"`
+}
+
+// ResolveGrantResponse is returned once a grantee has proven ownership of
+// their grant.
+type ResolveGrantResponse struct {
+	JWTToken           string `json:"jwtToken"`
+	EncryptedKey       string `json:"encryptedKey"`
+	KeyDecryptionNonce string `json:"keyDecryptionNonce"`
+	Nonce              string `json:"nonce"`
+	OpsLimit           int64  `json:"opsLimit"`
+	MemLimit           int64  `json:"memLimit"`
+}
+
+// AddGrantRequest provisions or replaces a single grantee on a collection's
+// public-link ACL.
+type AddGrantRequest struct {
+	CollectionID       int64   `json:"collectionID"`
+	GranteeID          string  `json:"granteeID"`
+	AuthMethod         string  `json:"authMethod"`
+	EncryptedKey       string  `json:"encryptedKey"`
+	KeyDecryptionNonce string  `json:"keyDecryptionNonce"`
+	VerifierHash       string  `json:"verifierHash"`
+	Nonce              string  `json:"nonce"`
+	OpsLimit           int64   `json:"opsLimit"`
+	MemLimit           int64   `json:"memLimit"`
+	PublicKey          *string `json:"publicKey,omitempty"`
+}
+
+// GetCollection fetches a single collection the caller owns or has access
+// to, including its EncryptedKey and PublicURLs, so the owner-facing grant
+// commands can resolve an album name to the collection they need to act on.
+func (c *Client) GetCollection(ctx context.Context, collectionID int64) (*Collection, error) {
+	var res Collection
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetResult(&res).
+		Get("/collections/" + strconv.FormatInt(collectionID, 10))
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// AddGrant provisions or replaces a grant on a collection the caller owns.
+func (c *Client) AddGrant(ctx context.Context, req AddGrantRequest) (*Grant, error) {
+	var res Grant
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetBody(req).
+		SetResult(&res).
+		Post("/collections/grants")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// ListGrants returns every grantee currently provisioned on collectionID.
+func (c *Client) ListGrants(ctx context.Context, collectionID int64) ([]Grant, error) {
+	var res []Grant
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetQueryParam("collectionID", strconv.FormatInt(collectionID, 10)).
+		SetResult(&res).
+		Get("/collections/grants")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return res, nil
+}
+
+// RevokeGrant removes granteeID's access to collectionID's public link.
+func (c *Client) RevokeGrant(ctx context.Context, collectionID int64, granteeID string) error {
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetQueryParam("collectionID", strconv.FormatInt(collectionID, 10)).
+		SetQueryParam("granteeID", granteeID).
+		Delete("/collections/grants")
+	if err != nil {
+		return err
+	}
+	if r.IsError() {
+		return &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return nil
+}
+
+// GrantParams are the public, unauthenticated parameters a grantee needs to
+// derive the secret that was used to wrap their collection key.
+type GrantParams struct {
+	AuthMethod string `json:"authMethod"`
+	Nonce      string `json:"nonce"`
+	OpsLimit   int64  `json:"opsLimit"`
+	MemLimit   int64  `json:"memLimit"`
+}
+
+// GetGrantParams fetches the public parameters for granteeHint under
+// accessToken, the ACL-grant counterpart of the Nonce/OpsLimit/MemLimit
+// already exposed on a collection's PublicURL.
+func (c *Client) GetGrantParams(ctx context.Context, accessToken, granteeHint string) (*GrantParams, error) {
+	var res GrantParams
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetHeader("X-Auth-Access-Token", accessToken).
+		SetQueryParam("granteeHint", granteeHint).
+		SetResult(&res).
+		Get("/public-collection/grant-params")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// ResolveGrant is the ACL-aware counterpart to VerifyPublicAlbumPassword: it
+// proves ownership of the grant identified by granteeHint under accessToken,
+// returning a JWT plus that grantee's own encrypted collection key.
+func (c *Client) ResolveGrant(ctx context.Context, accessToken, granteeHint, proof string) (*ResolveGrantResponse, error) {
+	var res ResolveGrantResponse
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetHeader("X-Auth-Access-Token", accessToken).
+		SetBody(map[string]string{
+			"granteeHint": granteeHint,
+			"proof":       proof,
+		}).
+		SetResult(&res).
+		Post("/public-collection/resolve-grant")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"io"
+	"os"
 	"strconv"
 )
 
@@ -118,3 +120,76 @@ func (c *Client) DownloadPublicFile(ctx context.Context, creds PublicAlbumCreden
 	}
 	return nil
 }
+
+// DownloadPublicThumbnail downloads a file's thumbnail from a public
+// collection, used as a fallback for generating a preview when the file
+// has no pre-computed blurhash.
+func (c *Client) DownloadPublicThumbnail(ctx context.Context, creds PublicAlbumCredentials, fileID int64, destPath string) error {
+	req := c.downloadClient.R().
+		SetContext(ctx).
+		SetHeader("X-Auth-Access-Token", creds.AccessToken).
+		SetOutput(destPath)
+
+	if creds.AccessTokenJWT != "" {
+		req.SetHeader("X-Auth-Access-Token-JWT", creds.AccessTokenJWT)
+	}
+
+	r, err := req.Get("https://public-albums.ente.io/preview/?fileID=" + strconv.FormatInt(fileID, 10))
+	if err != nil {
+		return err
+	}
+	if r.IsError() {
+		return &ApiError{
+			StatusCode: r.StatusCode(),
+			Message:    r.String(),
+		}
+	}
+	return nil
+}
+
+// DownloadPublicFileRange resumes a download of a public collection file
+// starting at rangeStart bytes, appending the response onto an
+// already-partially-downloaded destPath. The server is expected to honor a
+// standard HTTP Range request and reply 206 Partial Content.
+func (c *Client) DownloadPublicFileRange(ctx context.Context, creds PublicAlbumCredentials, fileID int64, destPath string, rangeStart int64) error {
+	req := c.downloadClient.R().
+		SetContext(ctx).
+		SetHeader("X-Auth-Access-Token", creds.AccessToken).
+		SetHeader("Range", "bytes="+strconv.FormatInt(rangeStart, 10)+"-").
+		SetDoNotParseResponse(true)
+
+	if creds.AccessTokenJWT != "" {
+		req.SetHeader("X-Auth-Access-Token-JWT", creds.AccessTokenJWT)
+	}
+
+	r, err := req.Get("https://public-albums.ente.io/download/?fileID=" + strconv.FormatInt(fileID, 10))
+	if err != nil {
+		return err
+	}
+	defer r.RawBody().Close()
+	if r.IsError() {
+		return &ApiError{
+			StatusCode: r.StatusCode(),
+			Message:    r.String(),
+		}
+	}
+	// Any other success status means the Range header was ignored and the
+	// body is the whole file, which must not be appended to the fragment.
+	if r.StatusCode() != 206 {
+		return &ApiError{
+			StatusCode: r.StatusCode(),
+			Message:    "range request not honored",
+		}
+	}
+
+	out, err := os.OpenFile(destPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, r.RawBody()); err != nil {
+		return err
+	}
+	return nil
+}
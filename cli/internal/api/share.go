@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"strconv"
+)
+
+// CreatePublicURLRequest creates (or refreshes) a collection's single-
+// password public share link. Nonce/MemLimit/OpsLimit are only set when the
+// link is password protected; the collection key itself is never part of
+// this request.
+type CreatePublicURLRequest struct {
+	CollectionID int64   `json:"collectionID"`
+	DeviceLimit  int     `json:"deviceLimit"`
+	ValidTill    int64   `json:"validTill"`
+	Nonce        *string `json:"nonce,omitempty"`
+	MemLimit     *int64  `json:"memLimit,omitempty"`
+	OpsLimit     *int64  `json:"opsLimit,omitempty"`
+}
+
+// CreatePublicURL creates a public share link for a collection the caller
+// owns, returning the server-assigned access token (as PublicURL.URL) that
+// the caller must append its own base58-encoded collection key fragment to.
+func (c *Client) CreatePublicURL(ctx context.Context, req CreatePublicURLRequest) (*PublicURL, error) {
+	var res PublicURL
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetBody(req).
+		SetResult(&res).
+		Post("/collections/share-url")
+	if err != nil {
+		return nil, err
+	}
+	if r.IsError() {
+		return nil, &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return &res, nil
+}
+
+// RevokePublicURL disables collectionID's public share link.
+func (c *Client) RevokePublicURL(ctx context.Context, collectionID int64) error {
+	r, err := c.restClient.R().
+		SetContext(ctx).
+		SetQueryParam("collectionID", strconv.FormatInt(collectionID, 10)).
+		Delete("/collections/share-url")
+	if err != nil {
+		return err
+	}
+	if r.IsError() {
+		return &ApiError{StatusCode: r.StatusCode(), Message: r.String()}
+	}
+	return nil
+}
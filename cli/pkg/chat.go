@@ -0,0 +1,331 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/ente-io/cli/internal/api"
+	eCrypto "github.com/ente-io/cli/internal/crypto"
+	"github.com/ente-io/cli/pkg/model"
+	"github.com/ente-io/cli/utils/encoding"
+	"github.com/google/uuid"
+)
+
+// chatDiffPageSize bounds how many diff entries are requested per page; it
+// mirrors the server's default GetDiff limit.
+const chatDiffPageSize = 500
+
+// localChatKeyEnvelope is how the chat's symmetric message key is kept at
+// rest: sealed with the account's master key so a stolen config store alone
+// can't decrypt any chat content.
+type localChatKeyEnvelope struct {
+	EncryptedKey string `json:"encryptedKey"`
+	Nonce        string `json:"nonce"`
+}
+
+// localChatSession mirrors api.ChatSession but with EncryptedData/Header
+// replaced by the decrypted plaintext, for local storage and listing.
+type localChatSession struct {
+	SessionUUID string `json:"sessionUUID"`
+	Title       string `json:"title"`
+	UpdatedAt   int64  `json:"updatedAt"`
+	IsDeleted   bool   `json:"isDeleted"`
+}
+
+// localChatMessage mirrors api.ChatMessage but with EncryptedData/Header
+// replaced by the decrypted plaintext, for local storage and listing.
+type localChatMessage struct {
+	MessageUUID       string  `json:"messageUUID"`
+	SessionUUID       string  `json:"sessionUUID"`
+	ParentMessageUUID *string `json:"parentMessageUUID"`
+	Body              string  `json:"body"`
+	UpdatedAt         int64   `json:"updatedAt"`
+	IsDeleted         bool    `json:"isDeleted"`
+}
+
+// InitChatKey generates a new symmetric key for encrypting chat session and
+// message bodies, seals it with the account's master key for local storage,
+// and registers a fingerprint of it with the server so devices can tell
+// whether they share the same chat key.
+func (c *ClICtrl) InitChatKey(ctx context.Context) error {
+	chatKey := make([]byte, 32)
+	if _, err := rand.Read(chatKey); err != nil {
+		return fmt.Errorf("failed to generate chat key: %w", err)
+	}
+
+	nonce, sealed, err := eCrypto.SecretBoxSeal(chatKey, c.KeyHolder.MasterKey)
+	if err != nil {
+		return fmt.Errorf("failed to seal chat key: %w", err)
+	}
+	envelope := localChatKeyEnvelope{
+		EncryptedKey: encoding.EncodeBase64(sealed),
+		Nonce:        encoding.EncodeBase64(nonce),
+	}
+	envelopeJSON, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat key envelope: %w", err)
+	}
+	if err := c.PutConfigValue(ctx, model.ChatKeyConfigKey, envelopeJSON); err != nil {
+		return fmt.Errorf("failed to persist chat key: %w", err)
+	}
+
+	fingerprint := sha256.Sum256(chatKey)
+	if _, err := c.Client.UpsertChatKey(ctx, encoding.EncodeBase64(fingerprint[:])); err != nil {
+		return fmt.Errorf("failed to register chat key with server: %w", err)
+	}
+
+	log.Printf("Chat key initialized")
+	return nil
+}
+
+// localChatKey loads and unseals the chat key persisted by InitChatKey.
+func (c *ClICtrl) localChatKey(ctx context.Context) ([]byte, error) {
+	envelopeJSON, err := c.GetConfigValue(ctx, model.ChatKeyConfigKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelopeJSON) == 0 {
+		return nil, fmt.Errorf("chat key not initialized, run 'ente chat init-key' first")
+	}
+	var envelope localChatKeyEnvelope
+	if err := json.Unmarshal(envelopeJSON, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse chat key envelope: %w", err)
+	}
+	chatKey, err := eCrypto.SecretBoxOpen(
+		encoding.DecodeBase64(envelope.EncryptedKey),
+		encoding.DecodeBase64(envelope.Nonce),
+		c.KeyHolder.MasterKey,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal chat key: %w", err)
+	}
+	return chatKey, nil
+}
+
+// PullChatDiff syncs local chat state against the server, applying session
+// and message upserts and tombstones, and advances the locally-stored sync
+// cursor exactly like syncAlbumFilesMetadata does for album files: loop
+// GetDiff until a page comes back empty, advancing the cursor to each page's
+// server-reported Timestamp (the furthest UpdatedAt/DeletedAt in that page).
+func (c *ClICtrl) PullChatDiff(ctx context.Context) error {
+	chatKey, err := c.localChatKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	sinceTime, err := c.GetInt64ConfigValue(ctx, model.ChatSyncKey)
+	if err != nil {
+		return err
+	}
+
+	for {
+		diff, err := c.Client.GetChatDiff(ctx, sinceTime, chatDiffPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to fetch chat diff: %w", err)
+		}
+
+		total := len(diff.Sessions) + len(diff.Messages) + len(diff.Tombstones.Sessions) + len(diff.Tombstones.Messages)
+		if total == 0 {
+			break
+		}
+
+		for _, session := range diff.Sessions {
+			if err := c.applyChatSession(ctx, session, chatKey); err != nil {
+				return err
+			}
+		}
+		for _, message := range diff.Messages {
+			if err := c.applyChatMessage(ctx, message, chatKey); err != nil {
+				return err
+			}
+		}
+		for _, tombstone := range diff.Tombstones.Sessions {
+			if err := c.DeleteValue(ctx, model.ChatSessions, []byte(tombstone.SessionUUID)); err != nil {
+				return fmt.Errorf("failed to remove tombstoned session: %w", err)
+			}
+		}
+		for _, tombstone := range diff.Tombstones.Messages {
+			if err := c.DeleteValue(ctx, model.ChatMessages, []byte(tombstone.MessageUUID)); err != nil {
+				return fmt.Errorf("failed to remove tombstoned message: %w", err)
+			}
+		}
+
+		log.Printf("Synced %d chat updates", total)
+
+		if diff.Timestamp <= sinceTime {
+			break
+		}
+		sinceTime = diff.Timestamp
+		if err := c.PutConfigValue(ctx, model.ChatSyncKey, []byte(fmt.Sprintf("%d", sinceTime))); err != nil {
+			return fmt.Errorf("failed to persist chat sync cursor: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyChatSession decrypts an incoming session and writes it to the local
+// store, resolving a conflict with an already-stored session by last-writer-
+// wins on UpdatedAt.
+func (c *ClICtrl) applyChatSession(ctx context.Context, session api.ChatSession, chatKey []byte) error {
+	existingJSON, err := c.GetValue(ctx, model.ChatSessions, []byte(session.SessionUUID))
+	if err != nil {
+		return fmt.Errorf("failed to read existing session: %w", err)
+	}
+	if len(existingJSON) > 0 {
+		var existing localChatSession
+		if err := json.Unmarshal(existingJSON, &existing); err == nil && existing.UpdatedAt > session.UpdatedAt {
+			return nil
+		}
+	}
+
+	local := localChatSession{SessionUUID: session.SessionUUID, UpdatedAt: session.UpdatedAt, IsDeleted: session.IsDeleted}
+	if session.EncryptedData != nil && session.Header != nil {
+		_, title, err := eCrypto.DecryptChaChaBase64(*session.EncryptedData, chatKey, *session.Header)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt session %s: %w", session.SessionUUID, err)
+		}
+		local.Title = string(title)
+	}
+
+	localJSON, err := json.Marshal(local)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	return c.PutValue(ctx, model.ChatSessions, []byte(session.SessionUUID), localJSON)
+}
+
+// applyChatMessage decrypts an incoming message and writes it to the local
+// store, resolving a conflict with an already-stored message by last-writer-
+// wins on UpdatedAt.
+func (c *ClICtrl) applyChatMessage(ctx context.Context, message api.ChatMessage, chatKey []byte) error {
+	existingJSON, err := c.GetValue(ctx, model.ChatMessages, []byte(message.MessageUUID))
+	if err != nil {
+		return fmt.Errorf("failed to read existing message: %w", err)
+	}
+	if len(existingJSON) > 0 {
+		var existing localChatMessage
+		if err := json.Unmarshal(existingJSON, &existing); err == nil && existing.UpdatedAt > message.UpdatedAt {
+			return nil
+		}
+	}
+
+	local := localChatMessage{
+		MessageUUID:       message.MessageUUID,
+		SessionUUID:       message.SessionUUID,
+		ParentMessageUUID: message.ParentMessageUUID,
+		UpdatedAt:         message.UpdatedAt,
+		IsDeleted:         message.IsDeleted,
+	}
+	if message.EncryptedData != nil && message.Header != nil {
+		_, body, err := eCrypto.DecryptChaChaBase64(*message.EncryptedData, chatKey, *message.Header)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt message %s: %w", message.MessageUUID, err)
+		}
+		local.Body = string(body)
+	}
+
+	localJSON, err := json.Marshal(local)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return c.PutValue(ctx, model.ChatMessages, []byte(message.MessageUUID), localJSON)
+}
+
+// ListChatSessions lists every locally-known chat session, most recently
+// updated first.
+func (c *ClICtrl) ListChatSessions(ctx context.Context) ([]localChatSession, error) {
+	entriesJSON, err := c.ListValues(ctx, model.ChatSessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat sessions: %w", err)
+	}
+	sessions := make([]localChatSession, 0, len(entriesJSON))
+	for _, entryJSON := range entriesJSON {
+		var session localChatSession
+		if err := json.Unmarshal(entryJSON, &session); err != nil {
+			return nil, fmt.Errorf("failed to parse stored session: %w", err)
+		}
+		if session.IsDeleted {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].UpdatedAt > sessions[j].UpdatedAt })
+	return sessions, nil
+}
+
+// SendChatMessage creates a new session (if sessionUUID is empty) or reuses
+// an existing one, encrypts body with the local chat key, and upserts it to
+// the server. It returns the UUID of the session the message was sent to.
+func (c *ClICtrl) SendChatMessage(ctx context.Context, sessionUUID, title, body string) (string, error) {
+	chatKey, err := c.localChatKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if sessionUUID == "" {
+		sessionUUID = uuid.New().String()
+		if title == "" {
+			title = "New chat"
+		}
+		header, encryptedTitle, err := eCrypto.EncryptChaChaBase64([]byte(title), chatKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt session title: %w", err)
+		}
+		session, err := c.Client.UpsertChatSession(ctx, sessionUUID, encryptedTitle, header)
+		if err != nil {
+			return "", fmt.Errorf("failed to create chat session: %w", err)
+		}
+		if err := c.applyChatSession(ctx, *session, chatKey); err != nil {
+			return "", err
+		}
+	}
+
+	header, encryptedBody, err := eCrypto.EncryptChaChaBase64([]byte(body), chatKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt message body: %w", err)
+	}
+	messageUUID := uuid.New().String()
+	message, err := c.Client.UpsertChatMessage(ctx, messageUUID, sessionUUID, nil, encryptedBody, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to send chat message: %w", err)
+	}
+	if err := c.applyChatMessage(ctx, *message, chatKey); err != nil {
+		return "", err
+	}
+
+	return sessionUUID, nil
+}
+
+// DeleteChatSession tombstones a session on the server and applies the
+// tombstone locally.
+func (c *ClICtrl) DeleteChatSession(ctx context.Context, sessionUUID string) error {
+	tombstone, err := c.Client.DeleteChatSession(ctx, sessionUUID)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat session: %w", err)
+	}
+	if err := c.DeleteValue(ctx, model.ChatSessions, []byte(tombstone.SessionUUID)); err != nil {
+		return fmt.Errorf("failed to remove session locally: %w", err)
+	}
+	if err := c.PutConfigValue(ctx, model.ChatSyncKey, []byte(fmt.Sprintf("%d", tombstone.DeletedAt))); err != nil {
+		return fmt.Errorf("failed to persist chat sync cursor: %w", err)
+	}
+	return nil
+}
+
+// DeleteChatMessage tombstones a message on the server and applies the
+// tombstone locally.
+func (c *ClICtrl) DeleteChatMessage(ctx context.Context, messageUUID string) error {
+	tombstone, err := c.Client.DeleteChatMessage(ctx, messageUUID)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat message: %w", err)
+	}
+	if err := c.DeleteValue(ctx, model.ChatMessages, []byte(tombstone.MessageUUID)); err != nil {
+		return fmt.Errorf("failed to remove message locally: %w", err)
+	}
+	return nil
+}
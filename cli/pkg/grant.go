@@ -0,0 +1,232 @@
+package pkg
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ente-io/cli/internal/api"
+	eCrypto "github.com/ente-io/cli/internal/crypto"
+	"github.com/ente-io/cli/pkg/model"
+	"github.com/ente-io/cli/utils/encoding"
+	"github.com/google/uuid"
+)
+
+// grantArgonNonceSize is the size, in bytes, of the random salt generated
+// for each grant's Argon2 derivation.
+const grantArgonNonceSize = 16
+
+// grantVerifierContext domain-separates the proof value sent to the server
+// from the Argon2 secret used to wrap the grant's collection key. Without
+// this, the verifier stored in the grant row *is* the SecretBox key, so
+// anyone with read access to the grants table (a DB leak, a backup, an
+// operator) could decrypt every password-protected grant without ever
+// knowing the grantee's password.
+const grantVerifierContext = "ente-public-link-grant-verifier"
+
+// deriveGrantVerifier turns the Argon2 secret into a one-way proof: HMAC
+// with a fixed, public context string so the server can check a future
+// proof against the stored verifier without either value letting anyone
+// recover secret itself.
+func deriveGrantVerifier(secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(grantVerifierContext))
+	return mac.Sum(nil)
+}
+
+// Default Argon2 limits for grant passwords, matching the interactive
+// limits libsodium recommends for secrets that must be derived on every
+// access rather than cached.
+const (
+	grantDefaultOpsLimit = 2
+	grantDefaultMemLimit = 67108864 // 64MiB
+)
+
+// resolveOwnedAlbum resolves albumName, for an already logged-in account (see
+// loginFirstAccount), to the full collection (including its EncryptedKey and
+// PublicURLs) plus its decrypted collection key.
+func (c *ClICtrl) resolveOwnedAlbum(ctx context.Context, albumName string) (api.Collection, []byte, error) {
+	if err := c.fetchRemoteCollections(ctx); err != nil {
+		return api.Collection{}, nil, err
+	}
+	albums, err := c.getRemoteAlbums(ctx)
+	if err != nil {
+		return api.Collection{}, nil, err
+	}
+
+	var albumID int64
+	found := false
+	for _, album := range albums {
+		if album.IsDeleted || !strings.EqualFold(album.AlbumName, albumName) {
+			continue
+		}
+		albumID = album.ID
+		found = true
+		break
+	}
+	if !found {
+		return api.Collection{}, nil, fmt.Errorf("no owned album found with name %q", albumName)
+	}
+
+	collection, err := c.Client.GetCollection(ctx, albumID)
+	if err != nil {
+		return api.Collection{}, nil, fmt.Errorf("failed to fetch collection %d: %w", albumID, err)
+	}
+
+	collectionKey, err := eCrypto.SecretBoxOpen(
+		encoding.DecodeBase64(collection.EncryptedKey),
+		encoding.DecodeBase64(collection.KeyDecryptionNonce),
+		c.KeyHolder.MasterKey)
+	if err != nil {
+		return api.Collection{}, nil, fmt.Errorf("failed to decrypt collection key: %w", err)
+	}
+	return *collection, collectionKey, nil
+}
+
+func (c *ClICtrl) loginFirstAccount(ctx context.Context) (context.Context, error) {
+	accounts, err := c.GetAccounts(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no accounts configured, use 'ente account add'")
+	}
+	account := accounts[0]
+	secretInfo, err := c.KeyHolder.LoadSecrets(account)
+	if err != nil {
+		return nil, err
+	}
+	ctx = c.buildRequestContext(ctx, account, model.Filter{})
+	c.Client.AddToken(account.AccountKey(), base64.URLEncoding.EncodeToString(secretInfo.Token))
+	return ctx, nil
+}
+
+// AddAlbumGrant provisions (or replaces) password-based access for a single
+// recipient on albumName's public link, letting the owner revoke that one
+// recipient later without rotating the link's shared password or affecting
+// anyone else.
+func (c *ClICtrl) AddAlbumGrant(albumName, granteeID, password string) error {
+	albumName = strings.TrimSpace(albumName)
+	granteeID = strings.TrimSpace(granteeID)
+	if albumName == "" {
+		return fmt.Errorf("album name is required")
+	}
+	if granteeID == "" {
+		granteeID = uuid.New().String()
+	}
+	if password == "" {
+		return fmt.Errorf("a grant password is required, use --password")
+	}
+
+	ctx, err := c.loginFirstAccount(context.Background())
+	if err != nil {
+		return err
+	}
+
+	collection, collectionKey, err := c.resolveOwnedAlbum(ctx, albumName)
+	if err != nil {
+		return err
+	}
+	if len(collection.PublicURLs) == 0 {
+		return fmt.Errorf("album %q has no public link, share it first", albumName)
+	}
+
+	nonce := make([]byte, grantArgonNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate grant salt: %w", err)
+	}
+	nonceB64 := base64.StdEncoding.EncodeToString(nonce)
+
+	secret, err := eCrypto.DeriveArgonKey(password, nonceB64, grantDefaultMemLimit, grantDefaultOpsLimit)
+	if err != nil {
+		return fmt.Errorf("failed to derive grant secret: %w", err)
+	}
+
+	keyNonce, encryptedKey, err := eCrypto.SecretBoxSeal(collectionKey, secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt collection key for grantee: %w", err)
+	}
+
+	grant, err := c.Client.AddGrant(ctx, api.AddGrantRequest{
+		CollectionID:       collection.ID,
+		GranteeID:          granteeID,
+		AuthMethod:         "password",
+		EncryptedKey:       base64.StdEncoding.EncodeToString(encryptedKey),
+		KeyDecryptionNonce: base64.StdEncoding.EncodeToString(keyNonce),
+		VerifierHash:       base64.StdEncoding.EncodeToString(deriveGrantVerifier(secret)),
+		Nonce:              nonceB64,
+		OpsLimit:           grantDefaultOpsLimit,
+		MemLimit:           grantDefaultMemLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add grant: %w", err)
+	}
+
+	log.Printf("Granted %q access to album %q", grant.GranteeID, albumName)
+	// The fragment's key segment is only a placeholder: with a grantee hint,
+	// ParsePublicAlbumURL's caller takes the collection key from the grant.
+	log.Printf("Share this link: %s#%s-%s", collection.PublicURLs[0].URL, base58Encode([]byte{0}), grant.GranteeID)
+	return nil
+}
+
+// ListAlbumGrants prints every grantee currently provisioned on albumName's
+// public link.
+func (c *ClICtrl) ListAlbumGrants(albumName string) error {
+	albumName = strings.TrimSpace(albumName)
+	if albumName == "" {
+		return fmt.Errorf("album name is required")
+	}
+
+	ctx, err := c.loginFirstAccount(context.Background())
+	if err != nil {
+		return err
+	}
+
+	collection, _, err := c.resolveOwnedAlbum(ctx, albumName)
+	if err != nil {
+		return err
+	}
+
+	grants, err := c.Client.ListGrants(ctx, collection.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list grants: %w", err)
+	}
+	if len(grants) == 0 {
+		log.Printf("No grants on album %q", albumName)
+		return nil
+	}
+	for _, grant := range grants {
+		log.Printf("%s (auth: %s)", grant.GranteeID, grant.AuthMethod)
+	}
+	return nil
+}
+
+// RevokeAlbumGrant removes granteeID's access to albumName's public link.
+func (c *ClICtrl) RevokeAlbumGrant(albumName, granteeID string) error {
+	albumName = strings.TrimSpace(albumName)
+	granteeID = strings.TrimSpace(granteeID)
+	if albumName == "" || granteeID == "" {
+		return fmt.Errorf("both album name and grantee id are required")
+	}
+
+	ctx, err := c.loginFirstAccount(context.Background())
+	if err != nil {
+		return err
+	}
+
+	collection, _, err := c.resolveOwnedAlbum(ctx, albumName)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Client.RevokeGrant(ctx, collection.ID, granteeID); err != nil {
+		return fmt.Errorf("failed to revoke grant: %w", err)
+	}
+	log.Printf("Revoked %q's access to album %q", granteeID, albumName)
+	return nil
+}
@@ -0,0 +1,337 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ente-io/cli/internal/api"
+	eCrypto "github.com/ente-io/cli/internal/crypto"
+	"github.com/ente-io/cli/pkg/model"
+	"github.com/ente-io/cli/utils/encoding"
+)
+
+const (
+	mirrorStateFileName  = ".ente-mirror-state.json"
+	defaultMirrorWorkers = 4
+)
+
+// mirrorFileState is the per-file record kept in the mirror state file,
+// letting a re-run skip files that are already present with the same
+// content and resume the remote diff from the highest UpdationTime seen.
+type mirrorFileState struct {
+	Hash             string `json:"hash"`
+	LastUpdationTime int64  `json:"lastUpdationTime"`
+	Bytes            int64  `json:"bytes"`
+}
+
+type mirrorState struct {
+	Files map[string]mirrorFileState `json:"files"`
+}
+
+func loadMirrorState(dir string) (*mirrorState, error) {
+	data, err := os.ReadFile(filepath.Join(dir, mirrorStateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &mirrorState{Files: map[string]mirrorFileState{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state mirrorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse mirror state: %w", err)
+	}
+	if state.Files == nil {
+		state.Files = map[string]mirrorFileState{}
+	}
+	return &state, nil
+}
+
+// save writes the state file atomically: a temp file in the same directory
+// is synced and renamed over the destination, so a crash mid-write never
+// leaves a corrupt or partial state file behind.
+func (s *mirrorState) save(dir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".ente-mirror-state-*.json")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() // nolint: errcheck
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close() // nolint: errcheck
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(dir, mirrorStateFileName))
+}
+
+// DownloadAllFromPublicAlbum mirrors an entire public album to outputPath.
+// Unlike DownloadRandomFromPublicAlbum, it's resumable: a JSON state file at
+// the destination records each file's hash, UpdationTime, and size, so a
+// re-run only fetches the diff since the highest stored UpdationTime, skips
+// files that are already present with a matching hash, and resumes any
+// partially-downloaded file via a Range request. With prune, files that have
+// been removed from the album upstream are deleted locally too.
+func (c *ClICtrl) DownloadAllFromPublicAlbum(albumURL, outputPath, fileType, password string, concurrency int, prune bool) error {
+	if outputPath == "" {
+		outputPath = "."
+	}
+	if strings.Contains(outputPath, "..") {
+		return fmt.Errorf("output path cannot contain '..'")
+	}
+	outputPath = filepath.Clean(outputPath)
+	if err := os.MkdirAll(outputPath, 0o755); err != nil {
+		return fmt.Errorf("failed to prepare output directory %s: %w", outputPath, err)
+	}
+	if concurrency <= 0 {
+		concurrency = defaultMirrorWorkers
+	}
+
+	typeFilter, err := parseFileType(fileType)
+	if err != nil {
+		return err
+	}
+
+	ctx, creds, _, collectionKeyBytes, albumName, err := c.resolvePublicAlbum(albumURL, password)
+	if err != nil {
+		return err
+	}
+	log.Printf("Mirroring album %s to %s", albumName, outputPath)
+
+	state, err := loadMirrorState(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to load mirror state: %w", err)
+	}
+
+	var sinceTime int64
+	for _, entry := range state.Files {
+		if entry.LastUpdationTime > sinceTime {
+			sinceTime = entry.LastUpdationTime
+		}
+	}
+
+	log.Printf("Fetching changes since last mirror...")
+	files, removedIDs, _, err := c.fetchPublicAlbumFilesSince(ctx, creds, collectionKeyBytes, sinceTime)
+	if err != nil {
+		return fmt.Errorf("failed to fetch files: %w", err)
+	}
+
+	var candidates []model.RemoteFile
+	for _, f := range files {
+		if typeFilter != nil && f.GetFileType() != *typeFilter {
+			continue
+		}
+		candidates = append(candidates, f)
+	}
+	log.Printf("%d files to check, %d removed upstream", len(candidates), len(removedIDs))
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		jobs     = make(chan model.RemoteFile)
+		firstErr error
+		done     int
+	)
+
+	worker := func() {
+		defer wg.Done()
+		for file := range jobs {
+			err := c.mirrorOneFile(ctx, creds, file, collectionKeyBytes, outputPath, state, &mu)
+			mu.Lock()
+			done++
+			n := done
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+			if err != nil {
+				log.Printf("Warning: failed to mirror file %d: %v", file.ID, err)
+				continue
+			}
+			log.Printf("Mirrored %d/%d files", n, len(candidates))
+		}
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go worker()
+	}
+	for _, f := range candidates {
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+
+	if prune {
+		for _, id := range removedIDs {
+			key := strconv.FormatInt(id, 10)
+			if _, tracked := state.Files[key]; !tracked {
+				continue
+			}
+			if err := c.pruneMirroredFile(outputPath, id); err != nil {
+				log.Printf("Warning: failed to prune file %d: %v", id, err)
+				continue
+			}
+			delete(state.Files, key)
+			log.Printf("Pruned file %d (removed from album)", id)
+		}
+	}
+
+	if err := state.save(outputPath); err != nil {
+		return fmt.Errorf("failed to save mirror state: %w", err)
+	}
+
+	if firstErr != nil {
+		return fmt.Errorf("mirror completed with errors, see warnings above: %w", firstErr)
+	}
+	log.Printf("Mirror complete: %s", outputPath)
+	return nil
+}
+
+// mirrorOneFile brings a single remote file's local copy up to date,
+// skipping the download entirely when the state file records the file at an
+// unchanged UpdationTime and its local copy still exists. After a download,
+// the local copy is left as it is when it exists and the hash matches.
+func (c *ClICtrl) mirrorOneFile(ctx context.Context, creds api.PublicAlbumCredentials, file model.RemoteFile, collectionKey []byte, outputPath string, state *mirrorState, mu *sync.Mutex) error {
+	key := strconv.FormatInt(file.ID, 10)
+
+	mu.Lock()
+	existing, tracked := state.Files[key]
+	mu.Unlock()
+
+	dest := filepath.Join(outputPath, mirrorFileName(file))
+	if tracked && existing.LastUpdationTime >= file.LastUpdateTime {
+		if _, statErr := os.Stat(dest); statErr == nil {
+			return nil
+		}
+	}
+
+	decryptedPath, err := c.downloadAndDecryptPublicFileResumable(ctx, creds, file, collectionKey)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(decryptedPath) // nolint: errcheck
+
+	hash, size, err := hashFile(decryptedPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash downloaded file: %w", err)
+	}
+
+	_, destErr := os.Stat(dest)
+	if tracked && existing.Hash == hash && destErr == nil {
+		mu.Lock()
+		state.Files[key] = mirrorFileState{Hash: hash, LastUpdationTime: file.LastUpdateTime, Bytes: size}
+		mu.Unlock()
+		return nil
+	}
+
+	if err := safeMove(decryptedPath, dest); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	state.Files[key] = mirrorFileState{Hash: hash, LastUpdationTime: file.LastUpdateTime, Bytes: size}
+	mu.Unlock()
+	return nil
+}
+
+// downloadAndDecryptPublicFileResumable behaves like
+// downloadAndDecryptPublicFile, except that if tempFolder already has a
+// partial ".encrypted" fragment for this file (left behind by an
+// interrupted previous run), it resumes the download with a Range request
+// instead of starting over.
+func (c *ClICtrl) downloadAndDecryptPublicFileResumable(ctx context.Context, creds api.PublicAlbumCredentials, file model.RemoteFile, collectionKey []byte) (string, error) {
+	downloadPath := fmt.Sprintf("%s/%d.encrypted", c.tempFolder, file.ID)
+	decryptedPath := fmt.Sprintf("%s/%d.decrypted", c.tempFolder, file.ID)
+
+	if info, statErr := os.Stat(downloadPath); statErr == nil && info.Size() > 0 {
+		log.Printf("Resuming partial download for file %d from byte %d", file.ID, info.Size())
+		if err := c.Client.DownloadPublicFileRange(ctx, creds, file.ID, downloadPath, info.Size()); err != nil {
+			// The server may not support Range for this file; fall back to a
+			// clean restart rather than failing the whole mirror.
+			os.Remove(downloadPath)
+			if err := c.Client.DownloadPublicFile(ctx, creds, file.ID, downloadPath); err != nil {
+				return "", fmt.Errorf("failed to download file: %w", err)
+			}
+		}
+	} else if err := c.Client.DownloadPublicFile(ctx, creds, file.ID, downloadPath); err != nil {
+		return "", fmt.Errorf("failed to download file: %w", err)
+	}
+
+	fileKey := file.Key.MustDecrypt(collectionKey)
+	if err := eCrypto.DecryptFile(downloadPath, decryptedPath, fileKey, encoding.DecodeBase64(file.FileNonce)); err != nil {
+		os.Remove(downloadPath)
+		return "", fmt.Errorf("failed to decrypt file: %w", err)
+	}
+
+	os.Remove(downloadPath)
+	return decryptedPath, nil
+}
+
+// pruneMirroredFile removes the local copy of a file that's been removed
+// from the album upstream.
+func (c *ClICtrl) pruneMirroredFile(outputPath string, fileID int64) error {
+	prefix := strconv.FormatInt(fileID, 10) + "_"
+	entries, err := os.ReadDir(outputPath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(outputPath, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mirrorFileName builds a stable, collision-free local filename for a
+// mirrored file: the file ID prefix lets pruneMirroredFile find it again
+// regardless of title changes, while the title suffix keeps it human
+// readable.
+func mirrorFileName(file model.RemoteFile) string {
+	title := filepath.Base(strings.TrimSpace(file.GetTitle()))
+	if title == "" || title == "." || title == string(filepath.Separator) {
+		title = fmt.Sprintf("%d", file.ID)
+	}
+	return fmt.Sprintf("%d_%s", file.ID, title)
+}
+
+func hashFile(path string) (hash string, size int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close() // nolint: errcheck
+
+	hasher := sha256.New()
+	size, err = io.Copy(hasher, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/buckket/go-blurhash"
+	"github.com/ente-io/cli/pkg/model"
+	"github.com/ente-io/cli/utils"
+)
+
+// previewGridWidth and previewGridHeight are the size of the terminal grid a
+// blurhash is rendered into. A 2:1 width:height ratio roughly compensates
+// for terminal cells themselves being about twice as tall as they are wide.
+const (
+	previewGridWidth  = 32
+	previewGridHeight = 16
+)
+
+// renderBlurhashPreview decodes hash into a small image and renders it as a
+// grid of truecolor ANSI background blocks.
+func renderBlurhashPreview(hash string) (string, error) {
+	img, err := blurhash.Decode(hash, previewGridWidth, previewGridHeight, 1)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode blurhash: %w", err)
+	}
+
+	var b strings.Builder
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			b.WriteString(fmt.Sprintf("\x1b[48;2;%d;%d;%dm \x1b[0m", r>>8, g>>8, bl>>8))
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// blurhashForThumbnail generates a blurhash from a downloaded thumbnail
+// image, for files uploaded before blurhash generation existed.
+func blurhashForThumbnail(thumbnailPath string) (string, error) {
+	f, err := os.Open(thumbnailPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open thumbnail: %w", err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+
+	hash, err := blurhash.Encode(4, 3, img)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode blurhash: %w", err)
+	}
+	return hash, nil
+}
+
+// previewAndConfirm renders a preview of file and asks the user whether to
+// proceed with the full download. fetchThumbnail is only called when file
+// has no pre-computed blurhash, and must return the path to a locally
+// downloaded (but not yet decoded) thumbnail image.
+func previewAndConfirm(file model.RemoteFile, fetchThumbnail func() (string, error)) (bool, error) {
+	hash := file.PublicMetadata.Blurhash
+	if hash == "" {
+		thumbnailPath, err := fetchThumbnail()
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch thumbnail for preview: %w", err)
+		}
+		defer os.Remove(thumbnailPath)
+
+		hash, err = blurhashForThumbnail(thumbnailPath)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	art, err := renderBlurhashPreview(hash)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Print(art)
+	fileName := strings.TrimSpace(file.GetTitle())
+	if fileName == "" {
+		fileName = fmt.Sprintf("%d", file.ID)
+	}
+	log.Printf("%s (%s)", fileName, utils.ByteCountDecimal(file.Info.FileSize))
+
+	fmt.Print("download? [y/N] ")
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -104,28 +104,62 @@ func base58Decode(input string) ([]byte, error) {
 	return decoded, nil
 }
 
-// ParsePublicAlbumURL extracts accessToken and collectionKey from a public album URL
-// URL format: https://albums.ente.io/?t=ACCESS_TOKEN#COLLECTION_KEY_BASE58
-func ParsePublicAlbumURL(albumURL string) (accessToken string, collectionKey []byte, err error) {
+// base58Encode encodes bytes to a base58 string using the same alphabet,
+// the inverse of base58Decode. Used to build a share link's key fragment
+// without ever sending the collection key itself to the server.
+func base58Encode(input []byte) string {
+	value := new(big.Int).SetBytes(input)
+	zero := big.NewInt(0)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+
+	var result []byte
+	for value.Cmp(zero) > 0 {
+		value.DivMod(value, base, mod)
+		result = append([]byte{base58Alphabet[mod.Int64()]}, result...)
+	}
+
+	// Preserve leading zero bytes as leading '1's, matching base58Decode.
+	for _, b := range input {
+		if b != 0 {
+			break
+		}
+		result = append([]byte{base58Alphabet[0]}, result...)
+	}
+
+	return string(result)
+}
+
+// ParsePublicAlbumURL extracts the accessToken, collectionKey and (for an
+// ACL-grant link) the granteeHint from a public album URL.
+// URL format: https://albums.ente.io/?t=ACCESS_TOKEN#COLLECTION_KEY_BASE58[-GRANTEE_HINT]
+//
+// The hyphen-suffix identifies which recipient of a multi-recipient link is
+// using it: present, it means the fragment's key material is a placeholder
+// and the real collection key must instead be fetched (wrapped for that
+// grantee) via the grant-resolution flow. Absent, this is a legacy
+// single-password (or unlisted) link and the decoded key is used directly.
+func ParsePublicAlbumURL(albumURL string) (accessToken string, collectionKey []byte, granteeHint string, err error) {
 	parsed, err := url.Parse(albumURL)
 	if err != nil {
-		return "", nil, fmt.Errorf("invalid URL: %w", err)
+		return "", nil, "", fmt.Errorf("invalid URL: %w", err)
 	}
 
 	// Get access token from query parameter 't'
 	accessToken = parsed.Query().Get("t")
 	if accessToken == "" {
-		return "", nil, fmt.Errorf("missing access token (t parameter) in URL")
+		return "", nil, "", fmt.Errorf("missing access token (t parameter) in URL")
 	}
 
 	// Get collection key from URL fragment (after #)
 	fragment := parsed.Fragment
 	if fragment == "" {
-		return "", nil, fmt.Errorf("missing collection key (URL fragment) in URL")
+		return "", nil, "", fmt.Errorf("missing collection key (URL fragment) in URL")
 	}
 
-	// Remove any suffix after hyphen (e.g., #KEY-suffix)
+	// Split off the grantee hint after a hyphen (e.g., #KEY-granteeHint)
 	if idx := strings.Index(fragment, "-"); idx != -1 {
+		granteeHint = fragment[idx+1:]
 		fragment = fragment[:idx]
 	}
 
@@ -134,17 +168,17 @@ func ParsePublicAlbumURL(albumURL string) (accessToken string, collectionKey []b
 		// Base58 encoded
 		collectionKey, err = base58Decode(fragment)
 		if err != nil {
-			return "", nil, fmt.Errorf("failed to decode base58 collection key: %w", err)
+			return "", nil, "", fmt.Errorf("failed to decode base58 collection key: %w", err)
 		}
 	} else {
 		// Hex encoded (legacy)
 		collectionKey, err = hexDecode(fragment)
 		if err != nil {
-			return "", nil, fmt.Errorf("failed to decode hex collection key: %w", err)
+			return "", nil, "", fmt.Errorf("failed to decode hex collection key: %w", err)
 		}
 	}
 
-	return accessToken, collectionKey, nil
+	return accessToken, collectionKey, granteeHint, nil
 }
 
 // hexDecode decodes a hex string to bytes
@@ -170,8 +204,94 @@ func isFilePath(path string) bool {
 	return ext != "" && len(ext) <= 5 // reasonable extension length
 }
 
+// resolvePublicAlbum parses a public album URL, fetches its collection info,
+// completes password verification if the album requires it, and decrypts
+// the album name. It's the common setup shared by every public-album
+// operation (random download, mirror, ...).
+func (c *ClICtrl) resolvePublicAlbum(albumURL, password string) (ctx context.Context, creds api.PublicAlbumCredentials, collection api.Collection, collectionKey []byte, albumName string, err error) {
+	accessToken, collectionKeyBytes, granteeHint, err := ParsePublicAlbumURL(albumURL)
+	if err != nil {
+		return nil, api.PublicAlbumCredentials{}, api.Collection{}, nil, "", err
+	}
+
+	ctx = context.WithValue(context.Background(), "app", "photos")
+	creds = api.PublicAlbumCredentials{AccessToken: accessToken}
+
+	log.Printf("Fetching public album info...")
+	collectionInfo, err := c.Client.GetPublicCollectionInfo(ctx, creds)
+	if err != nil {
+		return nil, api.PublicAlbumCredentials{}, api.Collection{}, nil, "", fmt.Errorf("failed to fetch public album info: %w", err)
+	}
+	collection = collectionInfo.Collection
+
+	if granteeHint != "" {
+		if password == "" {
+			return nil, api.PublicAlbumCredentials{}, api.Collection{}, nil, "", fmt.Errorf("this link requires a grant password, use --password flag")
+		}
+		jwt, wrappedKey, err := c.resolveAlbumGrant(ctx, accessToken, granteeHint, password)
+		if err != nil {
+			return nil, api.PublicAlbumCredentials{}, api.Collection{}, nil, "", fmt.Errorf("grant verification failed: %w", err)
+		}
+		creds.AccessTokenJWT = jwt
+		collectionKeyBytes = wrappedKey
+	} else if len(collection.PublicURLs) > 0 {
+		publicURL := collection.PublicURLs[0]
+		if publicURL.PasswordEnabled {
+			if password == "" {
+				return nil, api.PublicAlbumCredentials{}, api.Collection{}, nil, "", fmt.Errorf("this album is password protected, use --password flag")
+			}
+			jwt, err := c.verifyPublicAlbumPassword(ctx, accessToken, password, publicURL)
+			if err != nil {
+				return nil, api.PublicAlbumCredentials{}, api.Collection{}, nil, "", fmt.Errorf("password verification failed: %w", err)
+			}
+			creds.AccessTokenJWT = jwt
+		}
+	}
+
+	albumName, err = decryptCollectionName(collection, collectionKeyBytes)
+	if err != nil {
+		albumName = fmt.Sprintf("Album-%d", collection.ID)
+		log.Printf("Warning: could not decrypt album name: %v", err)
+	}
+
+	return ctx, creds, collection, collectionKeyBytes, albumName, nil
+}
+
+// resolveAlbumGrant completes the ACL-grant counterpart of
+// verifyPublicAlbumPassword: it fetches the grant's public Argon2
+// parameters, derives the same secret the owner used to wrap this
+// grantee's collection key, proves ownership of it to the server, and
+// decrypts the returned collection key with that secret.
+func (c *ClICtrl) resolveAlbumGrant(ctx context.Context, accessToken, granteeHint, password string) (jwt string, collectionKey []byte, err error) {
+	params, err := c.Client.GetGrantParams(ctx, accessToken, granteeHint)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch grant parameters: %w", err)
+	}
+
+	secret, err := eCrypto.DeriveArgonKey(password, params.Nonce, int(params.MemLimit), int(params.OpsLimit))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to derive grant secret: %w", err)
+	}
+	proof := base64.StdEncoding.EncodeToString(deriveGrantVerifier(secret))
+
+	resp, err := c.Client.ResolveGrant(ctx, accessToken, granteeHint, proof)
+	if err != nil {
+		return "", nil, err
+	}
+
+	collectionKey, err = eCrypto.SecretBoxOpen(
+		encoding.DecodeBase64(resp.EncryptedKey),
+		encoding.DecodeBase64(resp.KeyDecryptionNonce),
+		secret)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decrypt grant collection key: %w", err)
+	}
+
+	return resp.JWTToken, collectionKey, nil
+}
+
 // DownloadRandomFromPublicAlbum downloads a random file from a public album link
-func (c *ClICtrl) DownloadRandomFromPublicAlbum(albumURL, outputPath, fileType, password string) error {
+func (c *ClICtrl) DownloadRandomFromPublicAlbum(albumURL, outputPath, fileType, password string, preview bool) error {
 	if outputPath == "" {
 		outputPath = "."
 	}
@@ -199,48 +319,10 @@ func (c *ClICtrl) DownloadRandomFromPublicAlbum(albumURL, outputPath, fileType,
 		return err
 	}
 
-	// Parse the public album URL
-	accessToken, collectionKeyBytes, err := ParsePublicAlbumURL(albumURL)
+	ctx, creds, _, collectionKeyBytes, albumName, err := c.resolvePublicAlbum(albumURL, password)
 	if err != nil {
 		return err
 	}
-
-	// Set up context with app=photos for API calls
-	ctx := context.WithValue(context.Background(), "app", "photos")
-	creds := api.PublicAlbumCredentials{
-		AccessToken: accessToken,
-	}
-
-	// Fetch collection info
-	log.Printf("Fetching public album info...")
-	collectionInfo, err := c.Client.GetPublicCollectionInfo(ctx, creds)
-	if err != nil {
-		return fmt.Errorf("failed to fetch public album info: %w", err)
-	}
-
-	// Check if password protected
-	collection := collectionInfo.Collection
-	if len(collection.PublicURLs) > 0 {
-		publicURL := collection.PublicURLs[0]
-		if publicURL.PasswordEnabled {
-			if password == "" {
-				return fmt.Errorf("this album is password protected, use --password flag")
-			}
-			// Verify password and get JWT
-			jwt, err := c.verifyPublicAlbumPassword(ctx, accessToken, password, publicURL)
-			if err != nil {
-				return fmt.Errorf("password verification failed: %w", err)
-			}
-			creds.AccessTokenJWT = jwt
-		}
-	}
-
-	// Decrypt collection name
-	albumName, err := decryptCollectionName(collection, collectionKeyBytes)
-	if err != nil {
-		albumName = fmt.Sprintf("Album-%d", collection.ID)
-		log.Printf("Warning: could not decrypt album name: %v", err)
-	}
 	log.Printf("Album: %s", albumName)
 
 	// Fetch files
@@ -280,6 +362,18 @@ func (c *ClICtrl) DownloadRandomFromPublicAlbum(albumURL, outputPath, fileType,
 		log.Printf("Warning: selected file is large (%s)", utils.ByteCountDecimal(chosen.Info.FileSize))
 	}
 
+	if preview {
+		proceed, err := previewAndConfirm(chosen, func() (string, error) {
+			return c.downloadAndDecryptPublicThumbnail(ctx, creds, chosen, collectionKeyBytes)
+		})
+		if err != nil {
+			return err
+		}
+		if !proceed {
+			return nil
+		}
+	}
+
 	// Download and decrypt the file
 	log.Printf("Downloading %s...", chosen.GetTitle())
 	decryptedPath, err := c.downloadAndDecryptPublicFile(ctx, creds, chosen, collectionKeyBytes)
@@ -381,29 +475,38 @@ func decryptCollectionName(collection api.Collection, collectionKey []byte) (str
 }
 
 func (c *ClICtrl) fetchPublicAlbumFiles(ctx context.Context, creds api.PublicAlbumCredentials, collectionKey []byte) ([]model.RemoteFile, error) {
-	var allFiles []model.RemoteFile
-	var sinceTime int64 = 0
+	files, _, _, err := c.fetchPublicAlbumFilesSince(ctx, creds, collectionKey, 0)
+	return files, err
+}
+
+// fetchPublicAlbumFilesSince pages through the public collection diff
+// starting at sinceTime, returning the live files, the IDs of files removed
+// from the album since sinceTime, and the highest UpdationTime observed so
+// a caller can resume from exactly this point next time.
+func (c *ClICtrl) fetchPublicAlbumFilesSince(ctx context.Context, creds api.PublicAlbumCredentials, collectionKey []byte, sinceTime int64) (files []model.RemoteFile, removedIDs []int64, maxUpdationTime int64, err error) {
+	maxUpdationTime = sinceTime
 
 	for {
-		diff, err := c.Client.GetPublicCollectionDiff(ctx, creds, sinceTime)
+		diff, err := c.Client.GetPublicCollectionDiff(ctx, creds, maxUpdationTime)
 		if err != nil {
-			return nil, err
+			return nil, nil, maxUpdationTime, err
 		}
 
 		for _, file := range diff.Diff {
+			if file.UpdationTime > maxUpdationTime {
+				maxUpdationTime = file.UpdationTime
+			}
 			if file.IsRemovedFromAlbum() {
+				removedIDs = append(removedIDs, file.ID)
 				continue
 			}
-			if file.UpdationTime > sinceTime {
-				sinceTime = file.UpdationTime
-			}
 
 			remoteFile, err := decryptPublicFile(file, collectionKey)
 			if err != nil {
 				log.Printf("Warning: failed to decrypt file %d: %v", file.ID, err)
 				continue
 			}
-			allFiles = append(allFiles, *remoteFile)
+			files = append(files, *remoteFile)
 		}
 
 		if !diff.HasMore {
@@ -411,7 +514,7 @@ func (c *ClICtrl) fetchPublicAlbumFiles(ctx context.Context, creds api.PublicAlb
 		}
 	}
 
-	return allFiles, nil
+	return files, removedIDs, maxUpdationTime, nil
 }
 
 func decryptPublicFile(file api.File, collectionKey []byte) (*model.RemoteFile, error) {
@@ -485,6 +588,27 @@ func (c *ClICtrl) downloadAndDecryptPublicFile(ctx context.Context, creds api.Pu
 	return decryptedPath, nil
 }
 
+// downloadAndDecryptPublicThumbnail downloads and decrypts just file's
+// thumbnail, for generating a preview when no blurhash was stored.
+func (c *ClICtrl) downloadAndDecryptPublicThumbnail(ctx context.Context, creds api.PublicAlbumCredentials, file model.RemoteFile, collectionKey []byte) (string, error) {
+	downloadPath := fmt.Sprintf("%s/%d.thumb.encrypted", c.tempFolder, file.ID)
+	decryptedPath := fmt.Sprintf("%s/%d.thumb.decrypted", c.tempFolder, file.ID)
+
+	if err := c.Client.DownloadPublicThumbnail(ctx, creds, file.ID, downloadPath); err != nil {
+		return "", fmt.Errorf("failed to download thumbnail: %w", err)
+	}
+
+	fileKey := file.Key.MustDecrypt(collectionKey)
+	err := eCrypto.DecryptFile(downloadPath, decryptedPath, fileKey, encoding.DecodeBase64(file.ThumbnailNonce))
+	if err != nil {
+		os.Remove(downloadPath)
+		return "", fmt.Errorf("failed to decrypt thumbnail: %w", err)
+	}
+
+	os.Remove(downloadPath)
+	return decryptedPath, nil
+}
+
 func publicRandomIndex(max int) (int, error) {
 	if max <= 0 {
 		return 0, fmt.Errorf("max must be positive")
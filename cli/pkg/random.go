@@ -26,7 +26,7 @@ func isFileOutputPath(path string) bool {
 	return ext != "" && len(ext) <= 5 // reasonable extension length
 }
 
-func (c *ClICtrl) DownloadRandomFromAlbum(albumName, outputPath, fileType string) error {
+func (c *ClICtrl) DownloadRandomFromAlbum(albumName, outputPath, fileType string, preview bool) error {
 	trimmedAlbum := strings.TrimSpace(albumName)
 	if trimmedAlbum == "" {
 		return fmt.Errorf("album name is required")
@@ -146,6 +146,18 @@ func (c *ClICtrl) DownloadRandomFromAlbum(albumName, outputPath, fileType string
 		log.Printf("Warning: selected file is large (%s)", formatSize(chosen.Info.FileSize))
 	}
 
+	if preview {
+		proceed, err := previewAndConfirm(chosen, func() (string, error) {
+			return c.downloadAndDecryptThumbnail(ctx, chosen)
+		})
+		if err != nil {
+			return err
+		}
+		if !proceed {
+			return nil
+		}
+	}
+
 	decryptedPath, err := c.downloadAndDecrypt(ctx, chosen, c.KeyHolder.DeviceKey)
 	if err != nil {
 		return err
@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ente-io/cli/internal/api"
+)
+
+// shareArgonNonceSize is the size, in bytes, of the random salt generated
+// for a share link's password derivation. Matches grantArgonNonceSize.
+const shareArgonNonceSize = 16
+
+// Default Argon2 limits for share link passwords, matching the grant flow's
+// interactive limits.
+const (
+	shareDefaultOpsLimit = 2
+	shareDefaultMemLimit = 67108864 // 64MiB
+)
+
+// CreateAlbumShare creates a public share link for albumName. If password is
+// non-empty, the link is password protected using an Argon2 envelope
+// derived entirely client-side; the server only ever receives the
+// resulting Nonce/MemLimit/OpsLimit, never the password or the collection
+// key. The printed URL embeds the collection key as a base58 fragment, the
+// same format ParsePublicAlbumURL expects.
+func (c *ClICtrl) CreateAlbumShare(albumName, password string, expires time.Duration, deviceLimit int) error {
+	albumName = strings.TrimSpace(albumName)
+	if albumName == "" {
+		return fmt.Errorf("album name is required")
+	}
+
+	ctx, err := c.loginFirstAccount(context.Background())
+	if err != nil {
+		return err
+	}
+
+	collection, collectionKey, err := c.resolveOwnedAlbum(ctx, albumName)
+	if err != nil {
+		return err
+	}
+
+	req := api.CreatePublicURLRequest{
+		CollectionID: collection.ID,
+		DeviceLimit:  deviceLimit,
+	}
+	if expires > 0 {
+		req.ValidTill = time.Now().Add(expires).UnixMicro()
+	}
+
+	if password != "" {
+		nonce := make([]byte, shareArgonNonceSize)
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("failed to generate share salt: %w", err)
+		}
+		nonceB64 := base64.StdEncoding.EncodeToString(nonce)
+		memLimit := int64(shareDefaultMemLimit)
+		opsLimit := int64(shareDefaultOpsLimit)
+		req.Nonce = &nonceB64
+		req.MemLimit = &memLimit
+		req.OpsLimit = &opsLimit
+	}
+
+	publicURL, err := c.Client.CreatePublicURL(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	shareURL := fmt.Sprintf("%s#%s", publicURL.URL, base58Encode(collectionKey))
+	log.Printf("Shared album %q", albumName)
+	log.Printf("Share this link: %s", shareURL)
+	if password != "" {
+		log.Printf("Recipients will need the password to unlock it")
+	}
+	return nil
+}
+
+// ListAlbumShares prints albumName's active public share link, if any.
+func (c *ClICtrl) ListAlbumShares(albumName string) error {
+	albumName = strings.TrimSpace(albumName)
+	if albumName == "" {
+		return fmt.Errorf("album name is required")
+	}
+
+	ctx, err := c.loginFirstAccount(context.Background())
+	if err != nil {
+		return err
+	}
+
+	collection, _, err := c.resolveOwnedAlbum(ctx, albumName)
+	if err != nil {
+		return err
+	}
+
+	if len(collection.PublicURLs) == 0 {
+		log.Printf("Album %q has no public share link", albumName)
+		return nil
+	}
+	for _, publicURL := range collection.PublicURLs {
+		log.Printf("%s (password protected: %v, device limit: %d)", publicURL.URL, publicURL.PasswordEnabled, publicURL.DeviceLimit)
+	}
+	return nil
+}
+
+// RevokeAlbumShare disables the public share link identified by albumURL,
+// reusing ParsePublicAlbumURL to resolve it back to a collection.
+func (c *ClICtrl) RevokeAlbumShare(albumURL string) error {
+	albumURL = strings.TrimSpace(albumURL)
+	if albumURL == "" {
+		return fmt.Errorf("album URL is required")
+	}
+	accessToken, _, _, err := ParsePublicAlbumURL(albumURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := c.loginFirstAccount(context.Background())
+	if err != nil {
+		return err
+	}
+
+	collectionInfo, err := c.Client.GetPublicCollectionInfo(ctx, api.PublicAlbumCredentials{AccessToken: accessToken})
+	if err != nil {
+		return fmt.Errorf("failed to resolve share link: %w", err)
+	}
+
+	if err := c.Client.RevokePublicURL(ctx, collectionInfo.Collection.ID); err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	log.Printf("Revoked share link for album %d", collectionInfo.Collection.ID)
+	return nil
+}
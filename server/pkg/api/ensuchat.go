@@ -25,13 +25,16 @@ type EnsuChatHandler struct {
 }
 
 const (
-	ensuChatEndpointUpsertKey     = "upsert_key"
-	ensuChatEndpointGetKey        = "get_key"
-	ensuChatEndpointUpsertSession = "upsert_session"
-	ensuChatEndpointUpsertMessage = "upsert_message"
-	ensuChatEndpointDeleteSession = "delete_session"
-	ensuChatEndpointDeleteMessage = "delete_message"
-	ensuChatEndpointGetDiff       = "get_diff"
+	ensuChatEndpointUpsertKey          = "upsert_key"
+	ensuChatEndpointGetKey             = "get_key"
+	ensuChatEndpointUpsertSession      = "upsert_session"
+	ensuChatEndpointUpsertMessage      = "upsert_message"
+	ensuChatEndpointDeleteSession      = "delete_session"
+	ensuChatEndpointDeleteMessage      = "delete_message"
+	ensuChatEndpointGetDiff            = "get_diff"
+	ensuChatEndpointUpsertSignedPreKey = "upsert_signed_prekey"
+	ensuChatEndpointUploadOneTimeKeys  = "upload_one_time_prekeys"
+	ensuChatEndpointFetchPreKeyBundle  = "fetch_prekey_bundle"
 )
 
 var (
@@ -48,6 +51,18 @@ var (
 		Name: "museum_ensu_chat_diff_items_total",
 		Help: "Number of ensu chat diff items returned",
 	}, []string{"entity"})
+	ensuChatPreKeyBundleFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "museum_ensu_chat_prekey_bundle_fetches_total",
+		Help: "Total number of ensu chat prekey bundle fetches, by whether a one-time prekey was consumed",
+	}, []string{"one_time_prekey"})
+	ensuChatPreKeyExhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "museum_ensu_chat_prekey_exhausted_total",
+		Help: "Total number of prekey bundle fetches served with no one-time prekey remaining",
+	}, []string{})
+	ensuChatSignedPreKeyRotations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "museum_ensu_chat_signed_prekey_rotations_total",
+		Help: "Total number of ensu chat signed prekey rotations",
+	}, []string{})
 )
 
 func observeEnsuChatMetrics(c *gin.Context, endpoint string, startTime time.Time) {
@@ -227,3 +242,64 @@ func (h *EnsuChatHandler) GetDiff(c *gin.Context) {
 	logEnsuChatDiff(c, request, resp)
 	c.JSON(http.StatusOK, resp)
 }
+
+// UpsertSignedPreKey...
+func (h *EnsuChatHandler) UpsertSignedPreKey(c *gin.Context) {
+	startTime := time.Now()
+	defer observeEnsuChatMetrics(c, ensuChatEndpointUpsertSignedPreKey, startTime)
+
+	var request model.UpsertSignedPreKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		handler.Error(c,
+			stacktrace.Propagate(ente.ErrBadRequest, fmt.Sprintf("Request binding failed %s", err)))
+		return
+	}
+	resp, err := h.Controller.UpsertSignedPreKey(c, request)
+	if err != nil {
+		handler.Error(c, stacktrace.Propagate(err, "Failed to upsert ensu chat signed prekey"))
+		return
+	}
+	ensuChatSignedPreKeyRotations.WithLabelValues().Inc()
+	c.JSON(http.StatusOK, resp)
+}
+
+// UploadOneTimePreKeys...
+func (h *EnsuChatHandler) UploadOneTimePreKeys(c *gin.Context) {
+	startTime := time.Now()
+	defer observeEnsuChatMetrics(c, ensuChatEndpointUploadOneTimeKeys, startTime)
+
+	var request model.UploadOneTimePreKeysRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		handler.Error(c,
+			stacktrace.Propagate(ente.ErrBadRequest, fmt.Sprintf("Request binding failed %s", err)))
+		return
+	}
+	if err := h.Controller.UploadOneTimePreKeys(c, request); err != nil {
+		handler.Error(c, stacktrace.Propagate(err, "Failed to upload ensu chat one-time prekeys"))
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// FetchPreKeyBundle...
+func (h *EnsuChatHandler) FetchPreKeyBundle(c *gin.Context) {
+	startTime := time.Now()
+	defer observeEnsuChatMetrics(c, ensuChatEndpointFetchPreKeyBundle, startTime)
+
+	peerUserID, err := strconv.ParseInt(c.Query("peerUserID"), 10, 64)
+	if err != nil {
+		handler.Error(c, stacktrace.Propagate(ente.ErrBadRequest, "Missing or invalid peerUserID"))
+		return
+	}
+	resp, err := h.Controller.FetchPreKeyBundle(c, peerUserID)
+	if err != nil {
+		handler.Error(c, stacktrace.Propagate(err, "Failed to fetch ensu chat prekey bundle"))
+		return
+	}
+	consumed := resp.OneTimePreKey != nil
+	ensuChatPreKeyBundleFetches.WithLabelValues(strconv.FormatBool(consumed)).Inc()
+	if !consumed {
+		ensuChatPreKeyExhausted.WithLabelValues().Inc()
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -2,7 +2,12 @@ package collections
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bluele/gcache"
 	"github.com/ente-io/museum/pkg/controller"
 	"github.com/ente-io/museum/pkg/controller/access"
 	"github.com/ente-io/museum/pkg/controller/email"
@@ -14,13 +19,21 @@ import (
 
 	"github.com/ente-io/museum/ente"
 	"github.com/ente-io/museum/pkg/repo"
-	"github.com/ente-io/museum/pkg/utils/time"
+	"github.com/ente-io/museum/pkg/storage"
+	entetime "github.com/ente-io/museum/pkg/utils/time"
+	"github.com/ente-io/museum/pkg/utils/tracing"
 	"github.com/ente-io/stacktrace"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
 	CollectionDiffLimit = 2500
+
+	// backupLeaseTTL is how long a BackupWithScope lease row lives. The
+	// refresher only renews it while the request is being handled; once the
+	// handler returns the lease is abandoned and the row expires after this TTL.
+	backupLeaseTTL = 5 * time.Minute
 )
 
 // CollectionController encapsulates logic that deals with collections
@@ -35,17 +48,35 @@ type CollectionController struct {
 	QueueRepo          *repo.QueueRepository
 	CastRepo           *cast.Repository
 	TaskRepo           *repo.TaskLockRepository
+	// MultiBackend routes a collection's file objects to the backend for its
+	// StorageClass. Nil disables storage-class validation and tiering, i.e.
+	// every collection is served by whatever single backend callers use
+	// elsewhere.
+	MultiBackend *storage.MultiBackend
+	// ScopeCache memoizes hierarchical descendant/ancestor expansion for a
+	// (userID, collectionID) pair so repeated sharing/backup jobs over deep
+	// trees don't recompute it on every call. Nil disables caching. Populate
+	// with NewScopeCache() at wiring time.
+	ScopeCache gcache.Cache
+	// GrantCtrl manages the public-link ACL (per-grantee wrapped collection
+	// keys) layered on top of a collection's PublicURL. Nil disables the
+	// grant endpoints.
+	GrantCtrl *public.GrantController
 }
 
 // Create creates a collection
-func (c *CollectionController) Create(collection ente.Collection, ownerID int64) (ente.Collection, error) {
+func (c *CollectionController) Create(collection ente.Collection, ownerID int64) (res ente.Collection, retErr error) {
+	_, span := tracing.StartSpan(context.Background(), "CollectionController.Create",
+		attribute.Int64("user.id", ownerID))
+	defer func() { tracing.RecordError(span, retErr); span.End() }()
+
 	// The key attribute check is to ensure that user does not end up uploading any files before actually setting the key attributes.
 	if _, keyErr := c.UserRepo.GetKeyAttributes(ownerID); keyErr != nil {
 		return ente.Collection{}, stacktrace.Propagate(keyErr, "Unable to get keyAttributes")
 	}
 	collectionType := collection.Type
 	collection.Owner.ID = ownerID
-	collection.UpdationTime = time.Microseconds()
+	collection.UpdationTime = entetime.Microseconds()
 	// [20th Dec 2022] Patch on server side untill majority of the existing mobile clients upgrade to a version higher > 0.7.0
 	// https://github.com/ente-io/photos-app/pull/725
 	if collection.Type == "CollectionType.album" {
@@ -54,6 +85,9 @@ func (c *CollectionController) Create(collection ente.Collection, ownerID int64)
 	if !array.StringInList(collection.Type, ente.ValidCollectionTypes) {
 		return ente.Collection{}, stacktrace.Propagate(fmt.Errorf("unexpected collection type %s", collection.Type), "")
 	}
+	if collection.StorageClass != "" && c.MultiBackend != nil && !array.StringInList(collection.StorageClass, c.MultiBackend.AllowedStorageClasses()) {
+		return ente.Collection{}, stacktrace.Propagate(ente.ErrBadRequest, fmt.Sprintf("unsupported storage class %s", collection.StorageClass))
+	}
 	collection, err := c.CollectionRepo.Create(collection)
 	if err != nil {
 		if err == ente.ErrUncategorizeCollectionAlreadyExists || err == ente.ErrFavoriteCollectionAlreadyExist {
@@ -68,6 +102,7 @@ func (c *CollectionController) Create(collection ente.Collection, ownerID int64)
 		}
 		return ente.Collection{}, stacktrace.Propagate(err, "")
 	}
+	c.invalidateScopeCache(ownerID)
 	return collection, nil
 }
 
@@ -112,11 +147,16 @@ func (c *CollectionController) GetFile(ctx *gin.Context, collectionID int64, fil
 
 // TrashV3 deletes a given collection and based on user input (TrashCollectionV3Request.KeepFiles as FALSE) , it will move all files present in the underlying collection
 // to trash.
-func (c *CollectionController) TrashV3(ctx *gin.Context, req ente.TrashCollectionV3Request) error {
+func (c *CollectionController) TrashV3(ctx *gin.Context, req ente.TrashCollectionV3Request) (err error) {
+	_, span := tracing.StartSpan(ctx.Request.Context(), "CollectionController.TrashV3",
+		attribute.Int64("collection.id", req.CollectionID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	if req.KeepFiles == nil {
 		return ente.ErrBadRequest
 	}
 	userID := auth.GetUserID(ctx.Request.Header)
+	span.SetAttributes(attribute.Int64("user.id", userID))
 	cID := req.CollectionID
 	resp, err := c.AccessCtrl.GetCollection(ctx, &access.GetCollectionParams{
 		CollectionID:   cID,
@@ -162,6 +202,7 @@ func (c *CollectionController) TrashV3(ctx *gin.Context, req ente.TrashCollectio
 	if err != nil {
 		return stacktrace.Propagate(err, "")
 	}
+	c.invalidateScopeCache(userID)
 	return nil
 }
 
@@ -210,6 +251,7 @@ func (c *CollectionController) HandleAccountDeletion(ctx context.Context, userID
 	if err != nil {
 		return stacktrace.Propagate(err, "failed to revoke cast token for user")
 	}
+	c.invalidateScopeCache(userID)
 	err = c.CollectionLinkCtrl.HandleAccountDeletion(ctx, userID, logger)
 	return stacktrace.Propagate(err, "")
 }
@@ -227,7 +269,12 @@ func (c *CollectionController) verifyOwnership(cID int64, userID int64) error {
 }
 
 // SetParent sets the parent collection for nested collections
-func (c *CollectionController) SetParent(ctx context.Context, userID int64, collectionID int64, newParentID *int64) error {
+func (c *CollectionController) SetParent(ctx context.Context, userID int64, collectionID int64, newParentID *int64) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.SetParent",
+		attribute.Int64("collection.id", collectionID),
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	// Verify ownership of the collection being moved
 	if err := c.verifyOwnership(collectionID, userID); err != nil {
 		return stacktrace.Propagate(err, "User does not own collection")
@@ -240,26 +287,40 @@ func (c *CollectionController) SetParent(ctx context.Context, userID int64, coll
 		}
 		
 		// Check for circular reference
-		if err := c.checkCircularReference(collectionID, *newParentID); err != nil {
+		if err := c.checkCircularReference(collectionID, *newParentID, userID); err != nil {
 			return stacktrace.Propagate(err, "Would create circular reference")
 		}
+
+		if err := c.reconcileStorageClassOnMove(ctx, collectionID, *newParentID); err != nil {
+			return stacktrace.Propagate(err, "Storage class mismatch")
+		}
 	}
-	
+
 	// Update the parent collection
 	if err := c.CollectionRepo.SetParent(ctx, collectionID, newParentID); err != nil {
 		return stacktrace.Propagate(err, "Failed to set parent collection")
 	}
-	
+
+	// Reparenting invalidates both collectionID's ancestor chain and the
+	// descendant sets of everything above it.
+	c.invalidateScopeCache(userID)
+
 	// Update hierarchy paths
-	if err := c.updateHierarchyPaths(ctx, collectionID); err != nil {
+	if err := c.updateHierarchyPaths(ctx, collectionID, userID); err != nil {
 		return stacktrace.Propagate(err, "Failed to update hierarchy paths")
 	}
-	
+
 	return nil
 }
 
 // ShareWithScope shares a collection with specific scope
-func (c *CollectionController) ShareWithScope(ctx context.Context, userID int64, collectionID int64, request ente.ShareScopeRequest) (map[string]interface{}, error) {
+func (c *CollectionController) ShareWithScope(ctx context.Context, userID int64, collectionID int64, request ente.ShareScopeRequest) (res map[string]interface{}, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.ShareWithScope",
+		attribute.Int64("collection.id", collectionID),
+		attribute.Int64("user.id", userID),
+		attribute.String("scope", request.Scope))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	// Verify ownership
 	if err := c.verifyOwnership(collectionID, userID); err != nil {
 		return nil, stacktrace.Propagate(err, "User does not own collection")
@@ -287,7 +348,8 @@ func (c *CollectionController) ShareWithScope(ctx context.Context, userID int64,
 			subCollectionsCount = count
 		}
 	}
-	
+	span.SetAttributes(attribute.Int("descendant.count", subCollectionsCount))
+
 	return map[string]interface{}{
 		"success":               true,
 		"shared_count":          sharedCount,
@@ -295,8 +357,123 @@ func (c *CollectionController) ShareWithScope(ctx context.Context, userID int64,
 	}, nil
 }
 
+// AddGrant provisions or replaces a single grantee's wrapped collection key
+// on collectionID's public-link ACL, letting an owner mix password-based
+// and key-pair-based recipients on the same link.
+func (c *CollectionController) AddGrant(ctx context.Context, userID int64, collectionID int64, request ente.AddGrantRequest) (res repo.PublicLinkGrant, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.AddGrant",
+		attribute.Int64("collection.id", collectionID),
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err := c.verifyOwnership(collectionID, userID); err != nil {
+		return repo.PublicLinkGrant{}, stacktrace.Propagate(err, "User does not own collection")
+	}
+	if c.GrantCtrl == nil {
+		return repo.PublicLinkGrant{}, stacktrace.Propagate(ente.ErrBadRequest, "public link grants are not enabled")
+	}
+
+	grant, err := c.GrantCtrl.AddGrant(ctx, collectionID, request.GranteeID, request.AuthMethod, request.EncryptedKey, request.KeyDecryptionNonce, request.VerifierHash, request.Nonce, request.OpsLimit, request.MemLimit, request.PublicKey)
+	if err != nil {
+		return repo.PublicLinkGrant{}, stacktrace.Propagate(err, "Failed to add grant")
+	}
+	return grant, nil
+}
+
+// ListGrants returns every grantee currently provisioned on collectionID's
+// public-link ACL.
+func (c *CollectionController) ListGrants(ctx context.Context, userID int64, collectionID int64) (res []repo.PublicLinkGrant, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.ListGrants",
+		attribute.Int64("collection.id", collectionID),
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err := c.verifyOwnership(collectionID, userID); err != nil {
+		return nil, stacktrace.Propagate(err, "User does not own collection")
+	}
+	if c.GrantCtrl == nil {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "public link grants are not enabled")
+	}
+
+	grants, err := c.GrantCtrl.ListGrants(ctx, collectionID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to list grants")
+	}
+	return grants, nil
+}
+
+// RevokeGrant removes granteeID's access to collectionID's public link.
+// Every other grantee, and the link's shared password if one exists,
+// continues to work unaffected.
+func (c *CollectionController) RevokeGrant(ctx context.Context, userID int64, collectionID int64, granteeID string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.RevokeGrant",
+		attribute.Int64("collection.id", collectionID),
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err := c.verifyOwnership(collectionID, userID); err != nil {
+		return stacktrace.Propagate(err, "User does not own collection")
+	}
+	if c.GrantCtrl == nil {
+		return stacktrace.Propagate(ente.ErrBadRequest, "public link grants are not enabled")
+	}
+
+	if err := c.GrantCtrl.RevokeGrant(ctx, collectionID, granteeID); err != nil {
+		return stacktrace.Propagate(err, "Failed to revoke grant")
+	}
+	return nil
+}
+
+// CreatePublicLink creates (or refreshes) collectionID's single-password
+// public share link: a device limit, an optional expiry and an optional
+// password envelope (Argon2 Nonce/MemLimit/OpsLimit). The collection key
+// itself never reaches the server -- callers derive and keep it entirely
+// client-side, and only submit the parameters needed to later verify an
+// unlock attempt.
+func (c *CollectionController) CreatePublicLink(ctx context.Context, userID int64, collectionID int64, request ente.CreatePublicAccessTokenRequest) (res ente.PublicURL, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.CreatePublicLink",
+		attribute.Int64("collection.id", collectionID),
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err := c.verifyOwnership(collectionID, userID); err != nil {
+		return ente.PublicURL{}, stacktrace.Propagate(err, "User does not own collection")
+	}
+
+	publicURL, err := c.CollectionLinkCtrl.Create(ctx, collectionID, request)
+	if err != nil {
+		return ente.PublicURL{}, stacktrace.Propagate(err, "Failed to create public link")
+	}
+	return publicURL, nil
+}
+
+// RevokePublicLink disables collectionID's public share link. This reuses
+// the same CollectionLinkCtrl.Disable call TrashV3 already makes when a
+// collection is deleted.
+func (c *CollectionController) RevokePublicLink(ctx context.Context, userID int64, collectionID int64) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.RevokePublicLink",
+		attribute.Int64("collection.id", collectionID),
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err := c.verifyOwnership(collectionID, userID); err != nil {
+		return stacktrace.Propagate(err, "User does not own collection")
+	}
+
+	if err := c.CollectionLinkCtrl.Disable(ctx, collectionID); err != nil {
+		return stacktrace.Propagate(err, "Failed to revoke public link")
+	}
+	return nil
+}
+
 // BackupWithScope initiates backup with hierarchical scope
-func (c *CollectionController) BackupWithScope(ctx context.Context, userID int64, collectionID int64, request ente.BackupScopeRequest) (map[string]interface{}, error) {
+func (c *CollectionController) BackupWithScope(ctx context.Context, userID int64, collectionID int64, request ente.BackupScopeRequest) (res map[string]interface{}, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.BackupWithScope",
+		attribute.Int64("collection.id", collectionID),
+		attribute.Int64("user.id", userID),
+		attribute.String("scope", request.Scope))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	// Verify ownership
 	if err := c.verifyOwnership(collectionID, userID); err != nil {
 		return nil, stacktrace.Propagate(err, "User does not own collection")
@@ -307,23 +484,53 @@ func (c *CollectionController) BackupWithScope(ctx context.Context, userID int64
 		return nil, stacktrace.Propagate(ente.ErrBadRequest, "Invalid scope value")
 	}
 	
+	// Generate backup job ID
+	backupJobID := fmt.Sprintf("backup_%d_%d", collectionID, entetime.Microseconds())
+
+	// Acquire a lease keyed on (userID, collectionID, scope) so two requests
+	// for the same subtree don't redundantly enumerate it in parallel. A
+	// second overlapping request is told about the job already in flight
+	// instead of starting a competing one.
+	leaseKey := fmt.Sprintf("backup:%d:%d:%s", userID, collectionID, request.Scope)
+	lease, err := c.TaskRepo.AcquireLease(ctx, leaseKey, backupLeaseTTL, backupJobID)
+	if err != nil {
+		var held *repo.ErrLeaseHeld
+		if errors.As(err, &held) {
+			return map[string]interface{}{
+				"backup_job_id": held.Metadata,
+			}, stacktrace.Propagate(&ente.ApiError{
+				Code:           ente.BackupInProgress,
+				Message:        "A backup is already in progress for this scope",
+				HttpStatusCode: http.StatusConflict,
+			}, "")
+		}
+		return nil, stacktrace.Propagate(err, "Failed to acquire backup lease")
+	}
+	ctx = lease.Context
+	// The enumeration/upload this job ID names happens asynchronously, well
+	// past this request returning, so Release (which deletes the lease row
+	// immediately) would let a second request redundantly enumerate the same
+	// subtree the moment this handler returns. Abandon instead just stops
+	// our background refresher; the row is left to expire on its own after
+	// backupLeaseTTL, so it keeps rejecting overlapping requests for as long
+	// as the job it names is expected to still be running.
+	defer lease.Abandon()
+
 	// Get file count based on scope
 	var fileCount int
-	var err error
-	
+
 	if request.Scope == "direct_only" {
 		fileCount, err = c.CollectionRepo.GetFileCount(ctx, collectionID)
 	} else {
 		fileCount, err = c.CollectionRepo.GetHierarchicalFileCount(ctx, collectionID, request.ExcludedSubCollections)
 	}
-	
+
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "Failed to get file count")
 	}
-	
-	// Generate backup job ID
-	backupJobID := fmt.Sprintf("backup_%d_%d", collectionID, time.Microseconds())
-	
+
+	span.SetAttributes(attribute.Int("descendant.count", fileCount))
+
 	return map[string]interface{}{
 		"backup_job_id": backupJobID,
 		"files_count":   fileCount,
@@ -331,25 +538,34 @@ func (c *CollectionController) BackupWithScope(ctx context.Context, userID int64
 }
 
 // GetHierarchy returns the collection hierarchy for a user
-func (c *CollectionController) GetHierarchy(ctx context.Context, userID int64) ([]ente.Collection, error) {
+func (c *CollectionController) GetHierarchy(ctx context.Context, userID int64) (res []ente.Collection, err error) {
+	_, span := tracing.StartSpan(ctx, "CollectionController.GetHierarchy",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	collections, err := c.CollectionRepo.GetCollectionsOwnedByUserV2(userID, 0, ente.Photos, nil)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "Failed to get collections")
 	}
-	
+
 	// Build hierarchy structure
 	hierarchy := c.buildHierarchy(collections)
+	span.SetAttributes(attribute.Int("descendant.count", len(hierarchy)))
 	return hierarchy, nil
 }
 
 // SearchCollections searches for collections with hierarchy scope
-func (c *CollectionController) SearchCollections(ctx context.Context, userID int64, query string, scope string, collectionID *int64) ([]map[string]interface{}, error) {
+func (c *CollectionController) SearchCollections(ctx context.Context, userID int64, query string, scope string, collectionID *int64) (res []map[string]interface{}, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.SearchCollections",
+		attribute.Int64("user.id", userID),
+		attribute.String("scope", scope))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	var results []map[string]interface{}
-	
+
 	// Get collections based on scope
 	var collections []ente.Collection
-	var err error
-	
+
 	if scope == "current_folder" && collectionID != nil {
 		collections, err = c.CollectionRepo.SearchInCollection(ctx, userID, *collectionID, query)
 	} else {
@@ -381,9 +597,9 @@ func (c *CollectionController) SearchCollections(ctx context.Context, userID int
 }
 
 // Helper methods
-func (c *CollectionController) checkCircularReference(collectionID int64, parentID int64) error {
+func (c *CollectionController) checkCircularReference(collectionID int64, parentID int64, userID int64) error {
 	// Check if parentID is a descendant of collectionID
-	ancestors, err := c.CollectionRepo.GetAncestors(collectionID)
+	ancestors, err := c.cachedAncestors(collectionID, userID)
 	if err != nil {
 		return stacktrace.Propagate(err, "Failed to check ancestors")
 	}
@@ -393,25 +609,71 @@ func (c *CollectionController) checkCircularReference(collectionID int64, parent
 			return stacktrace.Propagate(ente.ErrBadRequest, "Circular reference detected")
 		}
 	}
-	
+
+	return nil
+}
+
+// reconcileStorageClassOnMove makes collectionID inherit newParentID's
+// storage class when it has none of its own. If collectionID already has an
+// explicit class that differs from the parent's, the move is rejected for
+// collections shared with others, since relocating a shared collection's
+// objects across backends out from under its grantees isn't something this
+// endpoint can do safely in-line; RelocateCollection should be used instead.
+func (c *CollectionController) reconcileStorageClassOnMove(ctx context.Context, collectionID int64, newParentID int64) error {
+	if c.MultiBackend == nil {
+		return nil
+	}
+	collection, err := c.CollectionRepo.Get(collectionID)
+	if err != nil {
+		return stacktrace.Propagate(err, "")
+	}
+	parent, err := c.CollectionRepo.Get(newParentID)
+	if err != nil {
+		return stacktrace.Propagate(err, "")
+	}
+	if collection.StorageClass == "" {
+		return stacktrace.Propagate(c.CollectionRepo.UpdateStorageClass(ctx, collectionID, parent.StorageClass), "failed to inherit storage class")
+	}
+	if collection.StorageClass != parent.StorageClass {
+		shareeCount, err := c.CollectionRepo.GetShareeCount(ctx, collectionID)
+		if err != nil {
+			return stacktrace.Propagate(err, "failed to check sharees")
+		}
+		if shareeCount > 0 {
+			return stacktrace.Propagate(ente.ErrBadRequest, "cannot move a shared collection across storage classes")
+		}
+	}
 	return nil
 }
 
-func (c *CollectionController) updateHierarchyPaths(ctx context.Context, collectionID int64) error {
+func (c *CollectionController) updateHierarchyPaths(ctx context.Context, collectionID int64, userID int64) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.updateHierarchyPaths",
+		attribute.Int64("collection.id", collectionID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	// Get all descendants and update their hierarchy paths
-	descendants, err := c.CollectionRepo.GetDescendants(ctx, collectionID)
+	descendants, err := c.cachedDescendants(ctx, collectionID, userID)
 	if err != nil {
 		return stacktrace.Propagate(err, "Failed to get descendants")
 	}
-	
+	span.SetAttributes(attribute.Int("descendant.count", len(descendants)))
+
 	for _, descendant := range descendants {
-		path, err := c.CollectionRepo.BuildHierarchyPath(ctx, descendant)
-		if err != nil {
+		pathCtx, pathSpan := tracing.StartSpan(ctx, "CollectionRepo.BuildHierarchyPath",
+			attribute.Int64("collection.id", descendant))
+		path, pathErr := c.CollectionRepo.BuildHierarchyPath(pathCtx, descendant)
+		tracing.RecordError(pathSpan, pathErr)
+		pathSpan.End()
+		if pathErr != nil {
 			continue // Skip on error but don't fail the whole operation
 		}
-		c.CollectionRepo.UpdateHierarchyPath(ctx, descendant, path)
+
+		updateCtx, updateSpan := tracing.StartSpan(ctx, "CollectionRepo.UpdateHierarchyPath",
+			attribute.Int64("collection.id", descendant))
+		c.CollectionRepo.UpdateHierarchyPath(updateCtx, descendant, path)
+		updateSpan.End()
 	}
-	
+
 	return nil
 }
 
@@ -0,0 +1,119 @@
+package collections
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ente-io/museum/ente"
+	"github.com/ente-io/museum/pkg/utils/tracing"
+	"github.com/ente-io/stacktrace"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HierarchicalDiffResponse is the response of GetHierarchicalDiff. It mirrors
+// the sessions/messages/tombstones/timestamp shape of
+// model.GetDiffResponse from EnsuChat, scoped to a collection subtree: a
+// single monotonic per-collection cursor a client can resume from instead of
+// stitching together N per-collection diffs.
+type HierarchicalDiffResponse struct {
+	Files []ente.File `json:"files"`
+	// CollectionSinceTime carries, for every collection walked in this call,
+	// the highest updation_time returned for it (sinceTime when none was).
+	// GetHierarchicalDiff itself takes a single sinceTime for the whole
+	// subtree; it does not accept these per-collection cursors as input.
+	CollectionSinceTime map[int64]int64 `json:"collectionSinceTime"`
+	// CollectionTombstones lists subtree collections trashed since sinceTime.
+	CollectionTombstones []int64 `json:"collectionTombstones"`
+	HasMore              bool    `json:"hasMore"`
+}
+
+// GetHierarchicalDiff returns a merged, time-ordered page of file diffs
+// across rootCollectionID and its full descendant subtree (reusing the
+// scope-expansion cache), capping total work at limit files and truncating
+// deterministically by (updation_time, file_id) so repeated calls with the
+// same sinceTime are reproducible.
+func (c *CollectionController) GetHierarchicalDiff(ctx context.Context, userID int64, rootCollectionID int64, sinceTime int64, limit int) (res *HierarchicalDiffResponse, err error) {
+	ctx, span := tracing.StartSpan(ctx, "CollectionController.GetHierarchicalDiff",
+		attribute.Int64("collection.id", rootCollectionID),
+		attribute.Int64("user.id", userID),
+		attribute.Int64("diff.since_time", sinceTime))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err := c.verifyOwnership(rootCollectionID, userID); err != nil {
+		return nil, stacktrace.Propagate(err, "User does not own collection")
+	}
+	if limit <= 0 {
+		limit = CollectionDiffLimit
+	}
+
+	descendants, err := c.cachedDescendants(ctx, rootCollectionID, userID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to get descendants")
+	}
+	subtree := append([]int64{rootCollectionID}, descendants...)
+	span.SetAttributes(attribute.Int("descendant.count", len(descendants)), attribute.Int("diff.limit", limit))
+
+	tombstones, err := c.CollectionRepo.GetTrashedDescendantsSince(ctx, subtree, sinceTime)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to get collection tombstones")
+	}
+
+	type fileWithCollection struct {
+		file ente.File
+		cID  int64
+	}
+	var merged []fileWithCollection
+	remaining := limit
+	sinceByCollection := make(map[int64]int64, len(subtree))
+	hasMore := false
+
+	for _, cID := range subtree {
+		sinceByCollection[cID] = sinceTime
+		if remaining <= 0 {
+			// Not queried in this page, so it may still hold unsent files.
+			hasMore = true
+			continue
+		}
+		files, err := c.FileRepo.GetDiff(ctx, cID, sinceTime, remaining)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Failed to get file diff")
+		}
+		for _, f := range files {
+			merged = append(merged, fileWithCollection{file: f, cID: cID})
+		}
+		if len(files) >= remaining {
+			hasMore = true
+		}
+		remaining -= len(files)
+	}
+
+	// Truncate deterministically by (updation_time, file_id) so a capped
+	// call is reproducible regardless of per-collection ordering above.
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].file.UpdationTime != merged[j].file.UpdationTime {
+			return merged[i].file.UpdationTime < merged[j].file.UpdationTime
+		}
+		return merged[i].file.ID < merged[j].file.ID
+	})
+
+	if len(merged) > limit {
+		hasMore = true
+		merged = merged[:limit]
+	}
+
+	files := make([]ente.File, 0, len(merged))
+	for _, entry := range merged {
+		files = append(files, entry.file)
+		if entry.file.UpdationTime > sinceByCollection[entry.cID] {
+			sinceByCollection[entry.cID] = entry.file.UpdationTime
+		}
+	}
+
+	span.SetAttributes(attribute.Bool("has_more", hasMore))
+	return &HierarchicalDiffResponse{
+		Files:                files,
+		CollectionSinceTime:  sinceByCollection,
+		CollectionTombstones: tombstones,
+		HasMore:              hasMore,
+	}, nil
+}
@@ -0,0 +1,95 @@
+package collections
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bluele/gcache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// scopeCacheSize bounds the number of (userID, collectionID) scope-expansion
+// entries kept in memory. Entries are evicted by least-frequently-used once
+// the bound is hit, mirroring the sizing used for other hot-path caches.
+const scopeCacheSize = 100_000
+
+var (
+	scopeCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "museum_ensu_chat_collection_scope_cache_requests_total",
+		Help: "Total number of CollectionController scope-expansion cache lookups by kind and result",
+	}, []string{"kind", "result"})
+)
+
+// scopeKind distinguishes the two directions of hierarchy expansion that get
+// memoized, since a collection's descendant set and ancestor set are cached
+// independently.
+type scopeKind string
+
+const (
+	scopeKindDescendants scopeKind = "descendants"
+	scopeKindAncestors   scopeKind = "ancestors"
+)
+
+func scopeCacheKey(kind scopeKind, userID int64, collectionID int64) string {
+	return fmt.Sprintf("%s:%d:%d", kind, userID, collectionID)
+}
+
+// NewScopeCache builds the bounded LFU cache used to memoize hierarchical
+// descendant/ancestor expansion for a (userID, collectionID) pair.
+func NewScopeCache() gcache.Cache {
+	return gcache.New(scopeCacheSize).LFU().Build()
+}
+
+// cachedDescendants returns the descendant set for collectionID, populating
+// the LFU cache on miss.
+func (c *CollectionController) cachedDescendants(ctx context.Context, collectionID int64, userID int64) ([]int64, error) {
+	return c.cachedScopeExpansion(scopeKindDescendants, userID, collectionID, func() ([]int64, error) {
+		return c.CollectionRepo.GetDescendants(ctx, collectionID)
+	})
+}
+
+// cachedAncestors returns the ancestor set for collectionID, populating the
+// LFU cache on miss.
+func (c *CollectionController) cachedAncestors(collectionID int64, userID int64) ([]int64, error) {
+	return c.cachedScopeExpansion(scopeKindAncestors, userID, collectionID, func() ([]int64, error) {
+		return c.CollectionRepo.GetAncestors(collectionID)
+	})
+}
+
+func (c *CollectionController) cachedScopeExpansion(kind scopeKind, userID int64, collectionID int64, load func() ([]int64, error)) ([]int64, error) {
+	if c.ScopeCache == nil {
+		return load()
+	}
+	key := scopeCacheKey(kind, userID, collectionID)
+	if cached, err := c.ScopeCache.Get(key); err == nil {
+		scopeCacheRequests.WithLabelValues(string(kind), "hit").Inc()
+		return cached.([]int64), nil
+	}
+	scopeCacheRequests.WithLabelValues(string(kind), "miss").Inc()
+	ids, err := load()
+	if err != nil {
+		return nil, err
+	}
+	_ = c.ScopeCache.Set(key, ids)
+	return ids, nil
+}
+
+// invalidateScopeCache drops every cached expansion that could be affected by
+// a hierarchy mutation in userID's collections. Reparenting/trashing/creating
+// a collection can change both its own ancestor chain and the descendant sets
+// of everything above it, so we purge both kinds for the whole owning user
+// rather than trying to pick out the exact keys touched.
+func (c *CollectionController) invalidateScopeCache(userID int64) {
+	if c.ScopeCache == nil {
+		return
+	}
+	for _, kind := range []scopeKind{scopeKindDescendants, scopeKindAncestors} {
+		prefix := fmt.Sprintf("%s:%d:", kind, userID)
+		for _, key := range c.ScopeCache.Keys(false) {
+			if s, ok := key.(string); ok && len(s) >= len(prefix) && s[:len(prefix)] == prefix {
+				c.ScopeCache.Remove(key)
+			}
+		}
+	}
+}
@@ -9,8 +9,10 @@ import (
 	model "github.com/ente-io/museum/ente/ensuchat"
 	"github.com/ente-io/museum/pkg/repo/ensuchat"
 	"github.com/ente-io/museum/pkg/utils/auth"
+	"github.com/ente-io/museum/pkg/utils/tracing"
 	"github.com/ente-io/stacktrace"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Controller exposes business logic for ensu chat.
@@ -18,77 +20,170 @@ type Controller struct {
 	Repo *ensuchat.Repository
 }
 
-func (c *Controller) UpsertKey(ctx *gin.Context, req model.UpsertKeyRequest) (*model.Key, error) {
+func (c *Controller) UpsertKey(ctx *gin.Context, req model.UpsertKeyRequest) (res *model.Key, err error) {
 	userID := auth.GetUserID(ctx.Request.Header)
-	res, err := c.Repo.UpsertKey(ctx, userID, req)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.UpsertKey",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	key, err := c.Repo.UpsertKey(ctx, userID, req)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "failed to upsert ensu chat key")
 	}
-	return &res, nil
+	return &key, nil
 }
 
-func (c *Controller) GetKey(ctx *gin.Context) (*model.Key, error) {
+func (c *Controller) GetKey(ctx *gin.Context) (res *model.Key, err error) {
 	userID := auth.GetUserID(ctx.Request.Header)
-	res, err := c.Repo.GetKey(ctx, userID)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.GetKey",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	key, err := c.Repo.GetKey(ctx, userID)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "failed to fetch ensu chat key")
 	}
-	return &res, nil
+	remaining, err := c.Repo.RemainingOneTimePreKeys(ctx, userID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to count remaining one-time prekeys")
+	}
+	key.RemainingOneTimePreKeys = remaining
+	return &key, nil
 }
 
-func (c *Controller) UpsertSession(ctx *gin.Context, req model.UpsertSessionRequest) (*model.Session, error) {
-	if err := c.validateKey(ctx); err != nil {
+// UpsertSignedPreKey rotates the caller's medium-term, identity-signed
+// prekey used to bootstrap X3DH sessions while they're offline.
+func (c *Controller) UpsertSignedPreKey(ctx *gin.Context, req model.UpsertSignedPreKeyRequest) (res *model.SignedPreKey, err error) {
+	userID := auth.GetUserID(ctx.Request.Header)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.UpsertSignedPreKey",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err = c.validateKey(ctx); err != nil {
 		return nil, stacktrace.Propagate(err, "failed to validateKey")
 	}
+	signedPreKey, err := c.Repo.UpsertSignedPreKey(ctx, userID, req)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to upsert ensu chat signed prekey")
+	}
+	return &signedPreKey, nil
+}
+
+// UploadOneTimePreKeys tops up the caller's pool of ephemeral one-time
+// prekeys, each of which FetchPreKeyBundle can hand out at most once.
+func (c *Controller) UploadOneTimePreKeys(ctx *gin.Context, req model.UploadOneTimePreKeysRequest) (err error) {
+	userID := auth.GetUserID(ctx.Request.Header)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.UploadOneTimePreKeys",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err = c.validateKey(ctx); err != nil {
+		return stacktrace.Propagate(err, "failed to validateKey")
+	}
+	if err = c.Repo.UploadOneTimePreKeys(ctx, userID, req.Keys); err != nil {
+		return stacktrace.Propagate(err, "failed to upload ensu chat one-time prekeys")
+	}
+	return nil
+}
+
+// FetchPreKeyBundle returns peerUserID's identity key, signed prekey, and (if
+// available) a freshly-consumed one-time prekey so the caller can establish
+// an X3DH session without peerUserID being online.
+func (c *Controller) FetchPreKeyBundle(ctx *gin.Context, peerUserID int64) (res *model.PreKeyBundle, err error) {
 	userID := auth.GetUserID(ctx.Request.Header)
-	res, err := c.Repo.UpsertSession(ctx, userID, req)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.FetchPreKeyBundle",
+		attribute.Int64("user.id", userID),
+		attribute.Int64("peer.id", peerUserID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err = c.validateKey(ctx); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to validateKey")
+	}
+	bundle, err := c.Repo.FetchPreKeyBundle(ctx, peerUserID)
 	if err != nil {
-		return nil, stacktrace.Propagate(err, "failed to upsert ensu chat session")
+		return nil, stacktrace.Propagate(err, "failed to fetch ensu chat prekey bundle")
 	}
-	return &res, nil
+	span.SetAttributes(attribute.Bool("one_time_prekey.consumed", bundle.OneTimePreKey != nil))
+	return &bundle, nil
 }
 
-func (c *Controller) UpsertMessage(ctx *gin.Context, req model.UpsertMessageRequest) (*model.Message, error) {
-	if err := c.validateKey(ctx); err != nil {
+func (c *Controller) UpsertSession(ctx *gin.Context, req model.UpsertSessionRequest) (res *model.Session, err error) {
+	userID := auth.GetUserID(ctx.Request.Header)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.UpsertSession",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err = c.validateKey(ctx); err != nil {
 		return nil, stacktrace.Propagate(err, "failed to validateKey")
 	}
+	session, err := c.Repo.UpsertSession(ctx, userID, req)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to upsert ensu chat session")
+	}
+	return &session, nil
+}
+
+func (c *Controller) UpsertMessage(ctx *gin.Context, req model.UpsertMessageRequest) (res *model.Message, err error) {
 	userID := auth.GetUserID(ctx.Request.Header)
-	res, err := c.Repo.UpsertMessage(ctx, userID, req)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.UpsertMessage",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err = c.validateKey(ctx); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to validateKey")
+	}
+	message, err := c.Repo.UpsertMessage(ctx, userID, req)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "failed to upsert ensu chat message")
 	}
-	return &res, nil
+	return &message, nil
 }
 
-func (c *Controller) DeleteSession(ctx *gin.Context, sessionUUID string) (*model.SessionTombstone, error) {
-	if err := c.validateKey(ctx); err != nil {
+func (c *Controller) DeleteSession(ctx *gin.Context, sessionUUID string) (res *model.SessionTombstone, err error) {
+	userID := auth.GetUserID(ctx.Request.Header)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.DeleteSession",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err = c.validateKey(ctx); err != nil {
 		return nil, stacktrace.Propagate(err, "failed to validateKey")
 	}
-	userID := auth.GetUserID(ctx.Request.Header)
-	res, err := c.Repo.DeleteSession(ctx, userID, sessionUUID)
+	tombstone, err := c.Repo.DeleteSession(ctx, userID, sessionUUID)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "failed to delete ensu chat session")
 	}
-	return &res, nil
+	return &tombstone, nil
 }
 
-func (c *Controller) DeleteMessage(ctx *gin.Context, messageUUID string) (*model.MessageTombstone, error) {
-	if err := c.validateKey(ctx); err != nil {
+func (c *Controller) DeleteMessage(ctx *gin.Context, messageUUID string) (res *model.MessageTombstone, err error) {
+	userID := auth.GetUserID(ctx.Request.Header)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.DeleteMessage",
+		attribute.Int64("user.id", userID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err = c.validateKey(ctx); err != nil {
 		return nil, stacktrace.Propagate(err, "failed to validateKey")
 	}
-	userID := auth.GetUserID(ctx.Request.Header)
-	res, err := c.Repo.DeleteMessage(ctx, userID, messageUUID)
+	tombstone, err := c.Repo.DeleteMessage(ctx, userID, messageUUID)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "failed to delete ensu chat message")
 	}
-	return &res, nil
+	return &tombstone, nil
 }
 
-func (c *Controller) GetDiff(ctx *gin.Context, req model.GetDiffRequest) (*model.GetDiffResponse, error) {
-	if err := c.validateKey(ctx); err != nil {
+func (c *Controller) GetDiff(ctx *gin.Context, req model.GetDiffRequest) (res *model.GetDiffResponse, err error) {
+	userID := auth.GetUserID(ctx.Request.Header)
+	_, span := tracing.StartSpan(ctx.Request.Context(), "ensuchat.Controller.GetDiff",
+		attribute.Int64("user.id", userID),
+		attribute.Int64("diff.limit", req.Limit))
+	if req.SinceTime != nil {
+		span.SetAttributes(attribute.Int64("diff.since_time", *req.SinceTime))
+	}
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if err = c.validateKey(ctx); err != nil {
 		return nil, stacktrace.Propagate(err, "failed to validateKey")
 	}
-	userID := auth.GetUserID(ctx.Request.Header)
 	remaining := int(req.Limit)
 
 	sessions := []model.SessionDiffEntry{}
@@ -1,77 +1,154 @@
 package llmchat
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/ente-io/museum/ente"
-	"github.com/ente-io/museum/pkg/utils/auth"
-	"github.com/ente-io/museum/pkg/utils/s3config"
+	"github.com/ente-io/museum/pkg/repo/llmchat"
+	"github.com/ente-io/museum/pkg/storage"
 	"github.com/ente-io/stacktrace"
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	llmChatAttachmentPrefix  = "llmchat/attachments"
-	llmChatMaxAttachmentSize = int64(100 * 1024 * 1024) // 100 MB
+	llmChatAttachmentPrefix     = "llmchat/attachments"
+	llmChatMaxAttachmentSize    = int64(100 * 1024 * 1024) // 100 MB
+	llmChatAttachmentObjectType = "application/octet-stream"
+
+	// sseCHeaderKey and sseCHeaderKeyMD5 let a client opt an individual
+	// attachment into SSE-C, on top of whatever SSE-KMS the operator may
+	// already have configured backend-wide. Both are base64 as S3 itself
+	// expects them.
+	sseCHeaderKey    = "X-Attachment-Encryption-Key"
+	sseCHeaderKeyMD5 = "X-Attachment-Encryption-Key-MD5"
+
+	// sseCKeySize is the only key length S3's SSE-C accepts (AES-256).
+	sseCKeySize = 32
 )
 
+// AttachmentController stores the raw bytes behind an llmchat attachment
+// blob (not to be confused with AttachFile, which links a message to an
+// existing file already in the caller's Ente library). Store is the same
+// pluggable storage.Backend abstraction collections use to tier across S3,
+// MinIO, Azure, and GCS, selected at startup by
+// llmchat.attachments.backend in museum.yaml -- self-hosters who already
+// run MinIO or a Tencent COS / Alibaba OSS bucket for media can point chat
+// attachments at it too, without forcing the AWS SDK path.
 type AttachmentController struct {
-	S3Config *s3config.S3Config
+	Store storage.Backend
+	// Repo records which session an attachment belongs to, so AttachmentGC
+	// can delete its object once that session is tombstoned. Nil disables
+	// that bookkeeping; uploads still succeed, they just won't be swept.
+	Repo *llmchat.Repository
+	// TokenSecret signs the scoped ?token= query parameter Upload/Download/
+	// Head accept as an alternative to the caller's own session JWT. Empty
+	// disables minting and verifying tokens entirely; every request then
+	// falls back to the JWT path it always used.
+	TokenSecret []byte
+}
+
+// AttachmentUploadResult is returned once an attachment's bytes have landed
+// in the store. Token is a scoped, time-limited credential that can
+// download (but not overwrite) this one attachment without the caller's
+// session JWT -- safe to embed in an exported transcript or hand to an
+// ephemeral agent. It's empty when TokenSecret isn't configured.
+type AttachmentUploadResult struct {
+	Token     string
+	ExpiresAt int64
 }
 
 func (c *AttachmentController) Upload(
 	ctx *gin.Context,
 	attachmentID string,
-) error {
+	sessionUUID string,
+) (*AttachmentUploadResult, error) {
 	if attachmentID == "" {
-		return stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id")
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id")
 	}
-	if c.S3Config == nil {
-		return stacktrace.Propagate(ente.ErrNotImplemented, "attachments not configured")
+	if c.Store == nil {
+		return nil, stacktrace.Propagate(ente.ErrNotImplemented, "attachments not configured")
 	}
 	if ctx.Request.ContentLength <= 0 {
-		return stacktrace.Propagate(ente.ErrBadRequest, "missing attachment size")
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment size")
 	}
 	if ctx.Request.ContentLength > llmChatMaxAttachmentSize {
-		return stacktrace.Propagate(
+		return nil, stacktrace.Propagate(
 			ente.ErrBadRequest,
 			"attachment size exceeds max %d bytes",
 			llmChatMaxAttachmentSize,
 		)
 	}
 
-	userID := auth.GetUserID(ctx.Request.Header)
+	sseKey, err := parseSSECHeaders(ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenWrite)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
 	objectKey := buildAttachmentObjectKey(userID, attachmentID)
-	bucket := c.S3Config.GetHotBucket()
-	s3Client := c.S3Config.GetHotS3Client()
 
 	// Skip upload if attachment already exists with same size
-	headOutput, err := s3Client.HeadObjectWithContext(ctx.Request.Context(), &s3.HeadObjectInput{
-		Bucket: bucket,
-		Key:    aws.String(objectKey),
-	})
-	if err == nil && headOutput.ContentLength != nil && *headOutput.ContentLength == ctx.Request.ContentLength {
+	if meta, headErr := c.Store.Head(ctx.Request.Context(), objectKey); headErr == nil && meta.Size == ctx.Request.ContentLength {
 		// Drain request body to avoid connection issues
 		_, _ = io.Copy(io.Discard, ctx.Request.Body)
-		return nil
+		if err := c.recordSessionAttachment(ctx, userID, sessionUUID, attachmentID); err != nil {
+			return nil, stacktrace.Propagate(err, "")
+		}
+		return c.finishUpload(attachmentID, userID)
+	}
+
+	if sseKey == nil {
+		if err := c.Store.Put(ctx.Request.Context(), objectKey, ctx.Request.Body, ctx.Request.ContentLength, llmChatAttachmentObjectType); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to upload attachment")
+		}
+	} else {
+		backend, err := c.sseCBackend()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "")
+		}
+		if err := backend.PutWithSSEC(ctx.Request.Context(), objectKey, ctx.Request.Body, ctx.Request.ContentLength, llmChatAttachmentObjectType, *sseKey); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to upload attachment with sse-c")
+		}
 	}
 
-	uploader := s3manager.NewUploaderWithClient(s3Client)
-	_, err = uploader.UploadWithContext(ctx.Request.Context(), &s3manager.UploadInput{
-		Bucket:      bucket,
-		Key:         aws.String(objectKey),
-		Body:        ctx.Request.Body,
-		ContentType: aws.String("application/octet-stream"),
-	})
+	if err := c.recordSessionAttachment(ctx, userID, sessionUUID, attachmentID); err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+	return c.finishUpload(attachmentID, userID)
+}
+
+// finishUpload mints a read token for the just-stored attachment. A missing
+// TokenSecret isn't an error -- the upload already succeeded, callers just
+// get an AttachmentUploadResult with an empty Token and must fall back to
+// their session JWT for subsequent downloads.
+func (c *AttachmentController) finishUpload(attachmentID string, userID int64) (*AttachmentUploadResult, error) {
+	token, expiresAt, err := c.MintAttachmentToken(attachmentID, userID, AttachmentTokenRead)
 	if err != nil {
-		return stacktrace.Propagate(err, "failed to upload attachment")
+		if errors.Is(err, ente.ErrNotImplemented) {
+			return &AttachmentUploadResult{}, nil
+		}
+		return nil, stacktrace.Propagate(err, "failed to mint attachment token")
 	}
+	return &AttachmentUploadResult{Token: token, ExpiresAt: expiresAt}, nil
+}
 
+// recordSessionAttachment hooks an upload into AttachmentGC's bookkeeping.
+// A nil Repo (or an empty sessionUUID) skips it without failing the upload;
+// a failure to record the row is returned, and callers fail the request
+// with it even though the object is already stored.
+func (c *AttachmentController) recordSessionAttachment(ctx *gin.Context, userID int64, sessionUUID string, attachmentID string) error {
+	if c.Repo == nil || sessionUUID == "" {
+		return nil
+	}
+	if err := c.Repo.RecordSessionAttachment(ctx.Request.Context(), userID, sessionUUID, attachmentID); err != nil {
+		return stacktrace.Propagate(err, "failed to record session attachment")
+	}
 	return nil
 }
 
@@ -82,44 +159,127 @@ func (c *AttachmentController) Download(
 	if attachmentID == "" {
 		return nil, 0, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id")
 	}
-	if c.S3Config == nil {
+	if c.Store == nil {
 		return nil, 0, stacktrace.Propagate(ente.ErrNotImplemented, "attachments not configured")
 	}
 
-	userID := auth.GetUserID(ctx.Request.Header)
+	sseKey, err := parseSSECHeaders(ctx)
+	if err != nil {
+		return nil, 0, stacktrace.Propagate(err, "")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenRead)
+	if err != nil {
+		return nil, 0, stacktrace.Propagate(err, "")
+	}
 	objectKey := buildAttachmentObjectKey(userID, attachmentID)
-	bucket := c.S3Config.GetHotBucket()
-	s3Client := c.S3Config.GetHotS3Client()
 
-	output, err := s3Client.GetObjectWithContext(ctx.Request.Context(), &s3.GetObjectInput{
-		Bucket: bucket,
-		Key:    aws.String(objectKey),
-	})
+	var body io.ReadCloser
+	var meta storage.ObjectMeta
+	if sseKey == nil {
+		body, meta, err = c.Store.Get(ctx.Request.Context(), objectKey)
+	} else {
+		var backend storage.SSECBackend
+		backend, err = c.sseCBackend()
+		if err == nil {
+			body, meta, err = backend.GetWithSSEC(ctx.Request.Context(), objectKey, *sseKey)
+		}
+	}
 	if err != nil {
-		if isAttachmentNotFound(err) {
+		if errors.Is(err, storage.ErrObjectNotFound) {
 			return nil, 0, stacktrace.Propagate(ente.ErrNotFound, "attachment not found")
 		}
+		if errors.Is(err, storage.ErrSSECKeyMismatch) {
+			return nil, 0, stacktrace.Propagate(ente.ErrBadRequest, "encryption key does not match attachment")
+		}
 		return nil, 0, stacktrace.Propagate(err, "failed to download attachment")
 	}
 
-	contentLength := int64(0)
-	if output.ContentLength != nil {
-		contentLength = *output.ContentLength
-	}
-
-	return output.Body, contentLength, nil
+	return body, meta.Size, nil
 }
 
 func buildAttachmentObjectKey(userID int64, attachmentID string) string {
 	return fmt.Sprintf("%s/%d/%s", llmChatAttachmentPrefix, userID, attachmentID)
 }
 
-func isAttachmentNotFound(err error) bool {
-	if awsErr, ok := err.(awserr.Error); ok {
-		switch awsErr.Code() {
-		case s3.ErrCodeNoSuchKey, "NotFound":
-			return true
-		}
+// parseSSECHeaders reads the optional X-Attachment-Encryption-Key(-MD5)
+// headers off a request. Both absent is the common case and returns (nil,
+// nil) -- the caller then proceeds along the plain (or operator-wide
+// SSE-KMS) path. Either header present without the other, or a key that
+// doesn't decode to exactly 32 bytes, is a client error.
+func parseSSECHeaders(ctx *gin.Context) (*storage.SSECustomerKey, error) {
+	keyHeader := ctx.GetHeader(sseCHeaderKey)
+	md5Header := ctx.GetHeader(sseCHeaderKeyMD5)
+	if keyHeader == "" && md5Header == "" {
+		return nil, nil
+	}
+	if keyHeader == "" || md5Header == "" {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "%s and %s must be supplied together", sseCHeaderKey, sseCHeaderKeyMD5)
+	}
+	key, err := base64.StdEncoding.DecodeString(keyHeader)
+	if err != nil {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "%s is not valid base64", sseCHeaderKey)
+	}
+	if len(key) != sseCKeySize {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "%s must decode to %d bytes", sseCHeaderKey, sseCKeySize)
+	}
+	return &storage.SSECustomerKey{Key: key, KeyMD5: md5Header}, nil
+}
+
+// sseCBackend type-asserts Store against storage.SSECBackend, reporting
+// ente.ErrBadRequest rather than panicking if a client sends SSE-C headers
+// against a backend that can't honor them.
+func (c *AttachmentController) sseCBackend() (storage.SSECBackend, error) {
+	backend, ok := c.Store.(storage.SSECBackend)
+	if !ok {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "configured backend does not support customer-provided encryption keys")
+	}
+	return backend, nil
+}
+
+// Head reports the size of an attachment without downloading its body --
+// used by clients resuming a stalled transfer. sseKey must match whatever
+// key (if any) the attachment was uploaded with.
+func (c *AttachmentController) Head(ctx *gin.Context, attachmentID string) (storage.ObjectMeta, error) {
+	if attachmentID == "" {
+		return storage.ObjectMeta{}, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id")
+	}
+	if c.Store == nil {
+		return storage.ObjectMeta{}, stacktrace.Propagate(ente.ErrNotImplemented, "attachments not configured")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenRead)
+	if err != nil {
+		return storage.ObjectMeta{}, stacktrace.Propagate(err, "")
+	}
+	objectKey := buildAttachmentObjectKey(userID, attachmentID)
+
+	sseKey, err := parseSSECHeaders(ctx)
+	if err != nil {
+		return storage.ObjectMeta{}, stacktrace.Propagate(err, "")
+	}
+	if sseKey == nil {
+		meta, err := c.Store.Head(ctx.Request.Context(), objectKey)
+		return meta, translateHeadErr(err)
+	}
+
+	backend, err := c.sseCBackend()
+	if err != nil {
+		return storage.ObjectMeta{}, stacktrace.Propagate(err, "")
+	}
+	meta, err := backend.HeadWithSSEC(ctx.Request.Context(), objectKey, *sseKey)
+	return meta, translateHeadErr(err)
+}
+
+func translateHeadErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return stacktrace.Propagate(ente.ErrNotFound, "attachment not found")
+	}
+	if errors.Is(err, storage.ErrSSECKeyMismatch) {
+		return stacktrace.Propagate(ente.ErrBadRequest, "encryption key does not match attachment")
 	}
-	return false
+	return stacktrace.Propagate(err, "failed to head attachment")
 }
@@ -0,0 +1,128 @@
+package llmchat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ente-io/museum/pkg/storage"
+	"github.com/ente-io/stacktrace"
+	log "github.com/sirupsen/logrus"
+)
+
+// attachmentGCSweepLimit bounds how many session_attachments rows a single
+// SweepTombstonedAttachments call processes, so one run can't hold an
+// unbounded query open.
+const attachmentGCSweepLimit = 1000
+
+// SweepTombstonedAttachments deletes the objects behind every attachment
+// whose session has been tombstoned since the last sweep, and marks those
+// rows swept so a later run doesn't revisit them. It's meant to be called
+// periodically by a background job, not inline with DeleteSession, since a
+// session can be tombstoned long before its attachments are swept.
+func (c *AttachmentController) SweepTombstonedAttachments(ctx context.Context) (swept int, err error) {
+	if c.Store == nil || c.Repo == nil {
+		return 0, nil
+	}
+
+	attachments, err := c.Repo.ListSweepableAttachments(ctx, attachmentGCSweepLimit)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "failed to list sweepable llmchat attachments")
+	}
+	if len(attachments) == 0 {
+		return 0, nil
+	}
+
+	idByKey := make(map[string]int64, len(attachments))
+	keys := make([]string, len(attachments))
+	for i, a := range attachments {
+		key := buildAttachmentObjectKey(a.UserID, a.AttachmentID)
+		keys[i] = key
+		idByKey[key] = a.ID
+	}
+
+	failedKeys, err := c.deleteObjects(ctx, keys)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "failed to delete swept llmchat attachment objects")
+	}
+	failed := make(map[string]bool, len(failedKeys))
+	for _, key := range failedKeys {
+		failed[key] = true
+	}
+
+	// Only rows whose object was actually deleted are marked swept -- a
+	// failed key is left alone so the next sweep retries it instead of
+	// the row being forgotten with its object still sitting in the store.
+	ids := make([]int64, 0, len(attachments))
+	for _, key := range keys {
+		if !failed[key] {
+			ids = append(ids, idByKey[key])
+		}
+	}
+	if err := c.Repo.MarkAttachmentsSwept(ctx, ids); err != nil {
+		return 0, stacktrace.Propagate(err, "failed to mark llmchat attachments swept")
+	}
+
+	if len(failedKeys) > 0 {
+		log.WithField("count", len(failedKeys)).Warn("failed to delete some llmchat attachment objects, will retry next sweep")
+	}
+	log.WithField("count", len(ids)).Info("swept tombstoned llmchat attachments")
+	return len(ids), nil
+}
+
+// HandleAccountDeletion purges every attachment object and bookkeeping row
+// userID has, regardless of whether its session was ever explicitly
+// tombstoned -- account deletion removes everything, not just what
+// SweepTombstonedAttachments would eventually have caught.
+func (c *AttachmentController) HandleAccountDeletion(ctx context.Context, userID int64, logger *log.Entry) error {
+	if c.Store == nil || c.Repo == nil {
+		return nil
+	}
+
+	attachmentIDs, err := c.Repo.ListAttachmentsForUser(ctx, userID)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to list llmchat attachments for user")
+	}
+	logger.WithField("count", len(attachmentIDs)).Info("purging llmchat attachments for deleted account")
+
+	keys := make([]string, len(attachmentIDs))
+	for i, attachmentID := range attachmentIDs {
+		keys[i] = buildAttachmentObjectKey(userID, attachmentID)
+	}
+	failedKeys, err := c.deleteObjects(ctx, keys)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to purge llmchat attachment objects")
+	}
+	if len(failedKeys) > 0 {
+		// Bookkeeping rows must survive a partial purge -- deleting them
+		// here would leave the still-undeleted objects with nothing
+		// tracking them for a retry.
+		return stacktrace.Propagate(fmt.Errorf("failed to purge %d of %d llmchat attachment objects", len(failedKeys), len(keys)), "")
+	}
+
+	if err := c.Repo.DeleteAttachmentRowsForUser(ctx, userID); err != nil {
+		return stacktrace.Propagate(err, "failed to delete llmchat session attachment rows")
+	}
+	return nil
+}
+
+// deleteObjects removes every key from Store, using BatchDeleter when the
+// backend supports it and falling back to one Delete call per key when it
+// doesn't. err is non-nil only on a request-level failure; per-key
+// failures inside an otherwise successful request are reported as
+// failedKeys, since callers need to know which rows are still safe to mark
+// swept (or delete bookkeeping for) and which aren't.
+func (c *AttachmentController) deleteObjects(ctx context.Context, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if batchDeleter, ok := c.Store.(storage.BatchDeleter); ok {
+		return batchDeleter.DeleteBatch(ctx, keys)
+	}
+	var failedKeys []string
+	for _, key := range keys {
+		if err := c.Store.Delete(ctx, key); err != nil {
+			failedKeys = append(failedKeys, key)
+		}
+	}
+	return failedKeys, nil
+}
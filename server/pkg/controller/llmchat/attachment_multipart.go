@@ -0,0 +1,265 @@
+package llmchat
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ente-io/museum/ente"
+	"github.com/ente-io/museum/pkg/storage"
+	"github.com/ente-io/stacktrace"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// attachmentMultipartPartSize is handed out to every client starting a
+	// multipart upload; it isn't negotiable per-upload since the object
+	// store needs every part but the last to be the same size.
+	attachmentMultipartPartSize = 10 * 1024 * 1024 // 10 MB
+
+	// attachmentMultipartStaleAfter bounds how long an upload a client
+	// never completed or came back to is allowed to sit before
+	// AbortStaleMultipartUploads reclaims it.
+	attachmentMultipartStaleAfter = 24 * time.Hour
+)
+
+// MultipartUploadInfo is returned when a client starts (or resumes) a
+// multipart attachment upload.
+type MultipartUploadInfo struct {
+	UploadID string
+	PartSize int64
+}
+
+// multipartBackend type-asserts Store against storage.MultipartBackend,
+// reporting ente.ErrNotImplemented for backends (or a nil Store) that don't
+// support it instead of letting a nil interface panic downstream.
+func (c *AttachmentController) multipartBackend() (storage.MultipartBackend, error) {
+	if c.Store == nil {
+		return nil, stacktrace.Propagate(ente.ErrNotImplemented, "attachments not configured")
+	}
+	backend, ok := c.Store.(storage.MultipartBackend)
+	if !ok {
+		return nil, stacktrace.Propagate(ente.ErrNotImplemented, "configured backend does not support multipart uploads")
+	}
+	return backend, nil
+}
+
+// CreateMultipartUpload starts a resumable upload for attachmentID. Calling
+// it again for the same attachmentID before Complete or Abort replaces the
+// prior upload, so a client that lost track of its uploadID can always
+// restart cleanly rather than being stuck.
+//
+// If the client supplies SSE-C headers, the same key must be repeated on
+// every UploadPart call for this upload -- museum doesn't persist it, since
+// doing so would defeat the point of a customer-supplied key.
+func (c *AttachmentController) CreateMultipartUpload(ctx *gin.Context, attachmentID string) (*MultipartUploadInfo, error) {
+	if attachmentID == "" {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id")
+	}
+	backend, err := c.multipartBackend()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+	if c.Repo == nil {
+		return nil, stacktrace.Propagate(ente.ErrNotImplemented, "attachments not configured")
+	}
+	sseKey, err := parseSSECHeaders(ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenWrite)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+	objectKey := buildAttachmentObjectKey(userID, attachmentID)
+
+	var uploadID string
+	if sseKey == nil {
+		uploadID, err = backend.CreateMultipartUpload(ctx.Request.Context(), objectKey)
+	} else {
+		sseBackend, ok := backend.(storage.SSECMultipartBackend)
+		if !ok {
+			return nil, stacktrace.Propagate(ente.ErrBadRequest, "configured backend does not support customer-provided encryption keys")
+		}
+		uploadID, err = sseBackend.CreateMultipartUploadWithSSEC(ctx.Request.Context(), objectKey, *sseKey)
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create multipart upload")
+	}
+
+	if _, err := c.Repo.CreateMultipartUpload(ctx.Request.Context(), userID, attachmentID, uploadID, attachmentMultipartPartSize); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to persist multipart upload")
+	}
+
+	return &MultipartUploadInfo{UploadID: uploadID, PartSize: attachmentMultipartPartSize}, nil
+}
+
+// UploadPart uploads one part of an in-progress multipart upload and
+// returns its ETag, which the client must echo back (with its partNumber)
+// to CompleteMultipartUpload.
+func (c *AttachmentController) UploadPart(ctx *gin.Context, attachmentID string, uploadID string, partNumber int) (string, error) {
+	if attachmentID == "" || uploadID == "" {
+		return "", stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id or upload id")
+	}
+	if partNumber < 1 {
+		return "", stacktrace.Propagate(ente.ErrBadRequest, "invalid part number")
+	}
+	backend, err := c.multipartBackend()
+	if err != nil {
+		return "", stacktrace.Propagate(err, "")
+	}
+	sseKey, err := parseSSECHeaders(ctx)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenWrite)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "")
+	}
+	objectKey := buildAttachmentObjectKey(userID, attachmentID)
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, llmChatMaxAttachmentSize))
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to read part body")
+	}
+
+	var etag string
+	if sseKey == nil {
+		etag, err = backend.UploadPart(ctx.Request.Context(), objectKey, uploadID, partNumber, body)
+	} else {
+		sseBackend, ok := backend.(storage.SSECMultipartBackend)
+		if !ok {
+			return "", stacktrace.Propagate(ente.ErrBadRequest, "configured backend does not support customer-provided encryption keys")
+		}
+		etag, err = sseBackend.UploadPartWithSSEC(ctx.Request.Context(), objectKey, uploadID, partNumber, body, *sseKey)
+	}
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to upload part")
+	}
+	return etag, nil
+}
+
+// CompleteMultipartUpload finalizes uploadID and records the resulting
+// object against sessionUUID exactly like a regular Upload would, so
+// AttachmentGC sweeps it the same way once the session is tombstoned.
+func (c *AttachmentController) CompleteMultipartUpload(ctx *gin.Context, attachmentID string, uploadID string, sessionUUID string, parts []storage.UploadedPart) (*AttachmentUploadResult, error) {
+	if attachmentID == "" || uploadID == "" {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id or upload id")
+	}
+	if len(parts) == 0 {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing parts")
+	}
+	backend, err := c.multipartBackend()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenWrite)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+	objectKey := buildAttachmentObjectKey(userID, attachmentID)
+
+	if err := backend.CompleteMultipartUpload(ctx.Request.Context(), objectKey, uploadID, parts); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to complete multipart upload")
+	}
+	if c.Repo != nil {
+		if err := c.Repo.DeleteMultipartUpload(ctx.Request.Context(), userID, attachmentID); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to clear multipart upload bookkeeping")
+		}
+	}
+
+	if err := c.recordSessionAttachment(ctx, userID, sessionUUID, attachmentID); err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+	return c.finishUpload(attachmentID, userID)
+}
+
+// ListParts reports every part the backend has already received for
+// uploadID, so a client that reconnected after losing track of its own
+// progress can resume from the first part it's actually missing instead of
+// re-uploading parts that already landed.
+func (c *AttachmentController) ListParts(ctx *gin.Context, attachmentID string, uploadID string) ([]storage.PartInfo, error) {
+	if attachmentID == "" || uploadID == "" {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id or upload id")
+	}
+	backend, err := c.multipartBackend()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenWrite)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+	objectKey := buildAttachmentObjectKey(userID, attachmentID)
+
+	parts, err := backend.ListParts(ctx.Request.Context(), objectKey, uploadID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to list parts")
+	}
+	return parts, nil
+}
+
+// AbortMultipartUpload cancels uploadID, releasing any storage its parts
+// were holding, and clears its bookkeeping row.
+func (c *AttachmentController) AbortMultipartUpload(ctx *gin.Context, attachmentID string, uploadID string) error {
+	if attachmentID == "" || uploadID == "" {
+		return stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id or upload id")
+	}
+	backend, err := c.multipartBackend()
+	if err != nil {
+		return stacktrace.Propagate(err, "")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenWrite)
+	if err != nil {
+		return stacktrace.Propagate(err, "")
+	}
+	objectKey := buildAttachmentObjectKey(userID, attachmentID)
+
+	if err := backend.AbortMultipartUpload(ctx.Request.Context(), objectKey, uploadID); err != nil {
+		return stacktrace.Propagate(err, "failed to abort multipart upload")
+	}
+	if c.Repo != nil {
+		if err := c.Repo.DeleteMultipartUpload(ctx.Request.Context(), userID, attachmentID); err != nil {
+			return stacktrace.Propagate(err, "failed to clear multipart upload bookkeeping")
+		}
+	}
+	return nil
+}
+
+// AbortStaleMultipartUploads aborts every multipart upload older than
+// attachmentMultipartStaleAfter, meant to be run periodically by a
+// scheduled job rather than inline with any single request.
+func (c *AttachmentController) AbortStaleMultipartUploads(ctx context.Context) (aborted int, err error) {
+	backend, err := c.multipartBackend()
+	if err != nil {
+		return 0, nil //nolint:nilerr // no multipart backend configured is not a job failure
+	}
+	if c.Repo == nil {
+		return 0, nil
+	}
+
+	uploads, err := c.Repo.ListStaleMultipartUploads(ctx, time.Now().Add(-attachmentMultipartStaleAfter), attachmentGCSweepLimit)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "failed to list stale llmchat multipart uploads")
+	}
+
+	for _, upload := range uploads {
+		objectKey := buildAttachmentObjectKey(upload.UserID, upload.AttachmentID)
+		if err := backend.AbortMultipartUpload(ctx, objectKey, upload.S3UploadID); err != nil {
+			return aborted, stacktrace.Propagate(err, "failed to abort stale llmchat multipart upload")
+		}
+		if err := c.Repo.DeleteMultipartUpload(ctx, upload.UserID, upload.AttachmentID); err != nil {
+			return aborted, stacktrace.Propagate(err, "failed to clear stale llmchat multipart upload")
+		}
+		aborted++
+	}
+
+	log.WithField("count", aborted).Info("aborted stale llmchat multipart uploads")
+	return aborted, nil
+}
@@ -0,0 +1,121 @@
+package llmchat
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ente-io/museum/ente"
+	"github.com/ente-io/museum/pkg/storage"
+	entetime "github.com/ente-io/museum/pkg/utils/time"
+	"github.com/ente-io/stacktrace"
+	"github.com/gin-gonic/gin"
+)
+
+// attachmentPresignTTL bounds how long a presigned attachment URL stays
+// valid, matching the default already used elsewhere for short-lived
+// signed URLs.
+const attachmentPresignTTL = 15 * time.Minute
+
+// UploadURL is a short-lived, presigned PUT a client streams attachment
+// bytes to directly, bypassing the museum HTTP handler entirely. Headers
+// holds the Content-Length and Content-Type values CreateUploadURL
+// validated. The presigned URL does not sign them, so the object store does
+// not check that the PUT actually sends these values.
+type UploadURL struct {
+	URL       string
+	Headers   map[string]string
+	ExpiresAt int64
+}
+
+// DownloadURL is a short-lived, presigned GET a client can read attachment
+// bytes from directly.
+type DownloadURL struct {
+	URL       string
+	ExpiresAt int64
+}
+
+// CreateUploadURL presigns a direct-to-object-store PUT for attachmentID,
+// the preferred upload path now that Upload's 100 MB proxy buffer is only a
+// fallback for clients that can't presign. contentLength is validated
+// exactly as Upload would validate it, since the object store enforces
+// whatever Content-Length the client actually sends, not what it declared
+// here.
+func (c *AttachmentController) CreateUploadURL(ctx *gin.Context, attachmentID string, sessionUUID string, contentLength int64) (*UploadURL, error) {
+	if attachmentID == "" {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id")
+	}
+	if c.Store == nil {
+		return nil, stacktrace.Propagate(ente.ErrNotImplemented, "attachments not configured")
+	}
+	if contentLength <= 0 {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment size")
+	}
+	if contentLength > llmChatMaxAttachmentSize {
+		return nil, stacktrace.Propagate(
+			ente.ErrBadRequest,
+			"attachment size exceeds max %d bytes",
+			llmChatMaxAttachmentSize,
+		)
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenWrite)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+	objectKey := buildAttachmentObjectKey(userID, attachmentID)
+
+	url, err := c.Store.PresignPut(ctx.Request.Context(), objectKey, attachmentPresignTTL)
+	if err != nil {
+		if errors.Is(err, storage.ErrPresignUnsupported) {
+			return nil, stacktrace.Propagate(ente.ErrNotImplemented, "configured backend cannot presign urls")
+		}
+		return nil, stacktrace.Propagate(err, "failed to presign attachment upload url")
+	}
+
+	// Recorded optimistically: the client hasn't actually PUT the bytes yet,
+	// but AttachmentGC only ever deletes rows whose session is tombstoned,
+	// so a presigned URL the client never used just sits harmlessly until
+	// then.
+	if err := c.recordSessionAttachment(ctx, userID, sessionUUID, attachmentID); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to record session attachment")
+	}
+
+	return &UploadURL{
+		URL: url,
+		Headers: map[string]string{
+			"Content-Length": strconv.FormatInt(contentLength, 10),
+			"Content-Type":   llmChatAttachmentObjectType,
+		},
+		ExpiresAt: entetime.Microseconds() + attachmentPresignTTL.Microseconds(),
+	}, nil
+}
+
+// CreateDownloadURL presigns a direct-to-object-store GET for attachmentID.
+func (c *AttachmentController) CreateDownloadURL(ctx *gin.Context, attachmentID string) (*DownloadURL, error) {
+	if attachmentID == "" {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing attachment id")
+	}
+	if c.Store == nil {
+		return nil, stacktrace.Propagate(ente.ErrNotImplemented, "attachments not configured")
+	}
+
+	userID, err := c.resolveCaller(ctx, attachmentID, AttachmentTokenRead)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "")
+	}
+	objectKey := buildAttachmentObjectKey(userID, attachmentID)
+
+	url, err := c.Store.PresignGet(ctx.Request.Context(), objectKey, attachmentPresignTTL)
+	if err != nil {
+		if errors.Is(err, storage.ErrPresignUnsupported) {
+			return nil, stacktrace.Propagate(ente.ErrNotImplemented, "configured backend cannot presign urls")
+		}
+		return nil, stacktrace.Propagate(err, "failed to presign attachment download url")
+	}
+
+	return &DownloadURL{
+		URL:       url,
+		ExpiresAt: entetime.Microseconds() + attachmentPresignTTL.Microseconds(),
+	}, nil
+}
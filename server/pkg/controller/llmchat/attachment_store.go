@@ -0,0 +1,115 @@
+package llmchat
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/ente-io/museum/pkg/storage"
+	"github.com/ente-io/stacktrace"
+)
+
+// AttachmentStoreConfig mirrors the llmchat.attachments block in
+// museum.yaml: a backend selector plus one credential/endpoint block per
+// backend kind, of which only the one named by Backend needs to be
+// populated.
+type AttachmentStoreConfig struct {
+	// Backend selects which block below is used: "s3", "minio", "cos", or
+	// "oss".
+	Backend string
+	S3      *S3AttachmentConfig
+	MinIO   *MinIOAttachmentConfig
+	Cos     *CosAttachmentConfig
+	Oss     *OssAttachmentConfig
+	// SSEKMSKeyID, if set, has every attachment object museum itself writes
+	// (Put and multipart uploads) transparently encrypted under this KMS key
+	// (llmchat.attachments.sseKmsKeyId in museum.yaml). Presigned PUT URLs
+	// from CreateUploadURL do not carry it. Only the s3 and minio backends
+	// honor it, since SSE-KMS
+	// is an S3 API concept; it's independent of the per-request SSE-C path
+	// AttachmentController supports via the X-Attachment-Encryption-Key
+	// headers.
+	SSEKMSKeyID string
+}
+
+type S3AttachmentConfig struct {
+	Region string
+	Bucket string
+}
+
+type MinIOAttachmentConfig struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+type CosAttachmentConfig struct {
+	BucketURL string
+	SecretID  string
+	SecretKey string
+}
+
+type OssAttachmentConfig struct {
+	Endpoint        string
+	Bucket          string
+	AccessKeyID     string
+	AccessKeySecret string
+}
+
+// NewAttachmentStore builds the storage.Backend named by cfg.Backend, so
+// AttachmentController's upload/download/head logic stays provider-agnostic
+// and a self-hoster already running MinIO, COS, or OSS for media can point
+// chat attachments at the same bucket without pulling in AWS credentials
+// they don't have.
+func NewAttachmentStore(cfg AttachmentStoreConfig) (storage.Backend, error) {
+	switch cfg.Backend {
+	case "s3":
+		if cfg.S3 == nil {
+			return nil, stacktrace.Propagate(fmt.Errorf("llmchat.attachments.s3 is not configured"), "")
+		}
+		sess, err := session.NewSession(&aws.Config{Region: aws.String(cfg.S3.Region)})
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "failed to create s3 session")
+		}
+		var opts []storage.S3BackendOption
+		if cfg.SSEKMSKeyID != "" {
+			opts = append(opts, storage.WithSSEKMSKeyID(cfg.SSEKMSKeyID))
+		}
+		return storage.NewS3Backend(sess, cfg.S3.Bucket, opts...), nil
+	case "minio":
+		if cfg.MinIO == nil {
+			return nil, stacktrace.Propagate(fmt.Errorf("llmchat.attachments.minio is not configured"), "")
+		}
+		var opts []storage.S3BackendOption
+		if cfg.SSEKMSKeyID != "" {
+			opts = append(opts, storage.WithSSEKMSKeyID(cfg.SSEKMSKeyID))
+		}
+		backend, err := storage.NewMinIOBackend(cfg.MinIO.Endpoint, cfg.MinIO.AccessKeyID, cfg.MinIO.SecretAccessKey, cfg.MinIO.Region, cfg.MinIO.Bucket, opts...)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "failed to create minio backend")
+		}
+		return backend, nil
+	case "cos":
+		if cfg.Cos == nil {
+			return nil, stacktrace.Propagate(fmt.Errorf("llmchat.attachments.cos is not configured"), "")
+		}
+		backend, err := storage.NewCosBackend(cfg.Cos.BucketURL, cfg.Cos.SecretID, cfg.Cos.SecretKey)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "failed to create cos backend")
+		}
+		return backend, nil
+	case "oss":
+		if cfg.Oss == nil {
+			return nil, stacktrace.Propagate(fmt.Errorf("llmchat.attachments.oss is not configured"), "")
+		}
+		backend, err := storage.NewOssBackend(cfg.Oss.Endpoint, cfg.Oss.AccessKeyID, cfg.Oss.AccessKeySecret, cfg.Oss.Bucket)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "failed to create oss backend")
+		}
+		return backend, nil
+	default:
+		return nil, stacktrace.Propagate(fmt.Errorf("unknown llmchat.attachments.backend %q", cfg.Backend), "")
+	}
+}
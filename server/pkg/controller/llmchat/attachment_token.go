@@ -0,0 +1,124 @@
+package llmchat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ente-io/museum/ente"
+	"github.com/ente-io/museum/pkg/utils/auth"
+	entetime "github.com/ente-io/museum/pkg/utils/time"
+	"github.com/ente-io/stacktrace"
+	"github.com/gin-gonic/gin"
+)
+
+// AttachmentTokenOp scopes an attachment token to a single operation, so a
+// token minted to make an exported transcript's download link work can't
+// also be replayed to overwrite the attachment it points at.
+type AttachmentTokenOp string
+
+const (
+	AttachmentTokenRead  AttachmentTokenOp = "read"
+	AttachmentTokenWrite AttachmentTokenOp = "write"
+)
+
+// attachmentTokenTTL bounds how long a minted attachment token stays valid.
+const attachmentTokenTTL = 24 * time.Hour
+
+// MintAttachmentToken issues a token scoping its bearer to a single
+// (attachmentID, op), HMAC-signed with TokenSecret so it can be verified
+// statelessly on every later Upload/Download/Head call rather than round
+// tripping to a database. It's how a download link can be embedded in an
+// exported transcript, or handed to an ephemeral agent, without exposing
+// the caller's own session JWT.
+func (c *AttachmentController) MintAttachmentToken(attachmentID string, userID int64, op AttachmentTokenOp) (token string, expiresAt int64, err error) {
+	if len(c.TokenSecret) == 0 {
+		return "", 0, stacktrace.Propagate(ente.ErrNotImplemented, "attachment tokens are not configured")
+	}
+	expiresAt = entetime.Microseconds() + attachmentTokenTTL.Microseconds()
+	return c.signAttachmentToken(attachmentID, userID, op, expiresAt), expiresAt, nil
+}
+
+func (c *AttachmentController) signAttachmentToken(attachmentID string, userID int64, op AttachmentTokenOp, expiresAt int64) string {
+	payload := attachmentTokenPayload(attachmentID, userID, op, expiresAt)
+	mac := hmac.New(sha256.New, c.TokenSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func attachmentTokenPayload(attachmentID string, userID int64, op AttachmentTokenOp, expiresAt int64) string {
+	return strings.Join([]string{attachmentID, strconv.FormatInt(userID, 10), string(op), strconv.FormatInt(expiresAt, 10)}, "|")
+}
+
+// verifyAttachmentToken checks token against attachmentID and op, returning
+// the userID it was minted for. A bad signature, an expired token, or one
+// minted for a different attachment/op are all reported identically as
+// ente.ErrPermissionDenied -- a caller presenting a bad token shouldn't
+// learn which part of it was wrong.
+func (c *AttachmentController) verifyAttachmentToken(token string, attachmentID string, op AttachmentTokenOp) (int64, error) {
+	if len(c.TokenSecret) == 0 {
+		return 0, stacktrace.Propagate(ente.ErrNotImplemented, "attachment tokens are not configured")
+	}
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "malformed attachment token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "malformed attachment token")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "malformed attachment token")
+	}
+
+	mac := hmac.New(sha256.New, c.TokenSecret)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "attachment token signature mismatch")
+	}
+
+	fields := strings.Split(string(payload), "|")
+	if len(fields) != 4 {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "malformed attachment token")
+	}
+	tokenAttachmentID, userIDField, opField, expiresAtField := fields[0], fields[1], fields[2], fields[3]
+
+	if tokenAttachmentID != attachmentID || AttachmentTokenOp(opField) != op {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "attachment token does not authorize this request")
+	}
+
+	expiresAt, err := strconv.ParseInt(expiresAtField, 10, 64)
+	if err != nil {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "malformed attachment token")
+	}
+	if entetime.Microseconds() > expiresAt {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "attachment token has expired")
+	}
+
+	userID, err := strconv.ParseInt(userIDField, 10, 64)
+	if err != nil {
+		return 0, stacktrace.Propagate(ente.ErrPermissionDenied, "malformed attachment token")
+	}
+	return userID, nil
+}
+
+// resolveCaller authorizes a single attachment request, accepting either the
+// standard museum user JWT (verified upstream, with userID already threaded
+// through the request header) or a scoped ?token= query parameter minted by
+// MintAttachmentToken. A token takes precedence when present -- it's the
+// mechanism for a caller acting without the user's own session to touch a
+// single attachment.
+func (c *AttachmentController) resolveCaller(ctx *gin.Context, attachmentID string, op AttachmentTokenOp) (int64, error) {
+	if token := ctx.Query("token"); token != "" {
+		userID, err := c.verifyAttachmentToken(token, attachmentID, op)
+		if err != nil {
+			return 0, stacktrace.Propagate(err, "failed to verify attachment token")
+		}
+		return userID, nil
+	}
+	return auth.GetUserID(ctx.Request.Header), nil
+}
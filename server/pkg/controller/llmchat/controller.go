@@ -4,10 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/ente-io/museum/ente"
 	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/museum/pkg/repo"
 	"github.com/ente-io/museum/pkg/repo/llmchat"
 	"github.com/ente-io/museum/pkg/utils/auth"
 	"github.com/ente-io/stacktrace"
@@ -19,6 +19,14 @@ import (
 type Controller struct {
 	Repo     *llmchat.Repository
 	KeyCache *cache.Cache
+	// Hub fans out live session/message/tombstone events to subscribed
+	// connections, keyed by userID. Nil disables Subscribe, leaving
+	// clients on the GetDiff poll loop.
+	Hub *Hub
+	// FileRepo resolves ownership of an existing Ente file so AttachFile
+	// can refuse to link a message to a file the caller doesn't own. Nil
+	// disables attachments entirely.
+	FileRepo *repo.FileRepository
 }
 
 func (c *Controller) UpsertKey(ctx *gin.Context, req model.UpsertKeyRequest) (*model.Key, error) {
@@ -89,6 +97,15 @@ func (c *Controller) DeleteMessage(ctx *gin.Context, messageUUID string) (*model
 	return &res, nil
 }
 
+// GetDiff returns everything that's changed since the caller's last sync,
+// as four independently-paginated streams. Each stream carries its own
+// opaque cursor (an encoded (updated_at, uuid) pair) and HasMore flag
+// instead of the diff as a whole being keyed off a single timestamp: two
+// rows sharing an updated_at microsecond are common under batch upserts,
+// and "maxTimestamp+1" as a next-cursor can skip straight past a sibling
+// row instead of returning it on the next page. A caller should keep
+// requesting with the returned cursors until every stream's HasMore is
+// false.
 func (c *Controller) GetDiff(ctx *gin.Context, req model.GetDiffRequest) (*model.GetDiffResponse, error) {
 	if err := c.validateKey(ctx); err != nil {
 		return nil, stacktrace.Propagate(err, "failed to validateKey")
@@ -96,51 +113,72 @@ func (c *Controller) GetDiff(ctx *gin.Context, req model.GetDiffRequest) (*model
 	userID := auth.GetUserID(ctx.Request.Header)
 	remaining := int(req.Limit)
 
+	sessionsSinceTS, sessionsSinceUUID, err := decodeDiffCursorOrSinceTime(req.SessionsCursor, req.SinceTime)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "invalid sessionsCursor")
+	}
+	messagesSinceTS, messagesSinceUUID, err := decodeDiffCursorOrSinceTime(req.MessagesCursor, req.SinceTime)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "invalid messagesCursor")
+	}
+	sessionTombstonesSinceTS, sessionTombstonesSinceUUID, err := decodeDiffCursorOrSinceTime(req.SessionTombstonesCursor, req.SinceTime)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "invalid sessionTombstonesCursor")
+	}
+	messageTombstonesSinceTS, messageTombstonesSinceUUID, err := decodeDiffCursorOrSinceTime(req.MessageTombstonesCursor, req.SinceTime)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "invalid messageTombstonesCursor")
+	}
+
 	sessions := []model.SessionDiffEntry{}
 	messages := []model.MessageDiffEntry{}
 	sessionTombstones := []model.SessionTombstone{}
 	messageTombstones := []model.MessageTombstone{}
+	// Default every stream's HasMore to true: the four streams share one
+	// limit budget, so an earlier stream can exhaust it before a later one
+	// is ever queried. An unqueried stream's real state is unknown, and
+	// reporting false would wrongly tell the caller it's fully synced and
+	// stop it from ever re-polling that stream's cursor. Only a stream that
+	// actually ran and came back with no more rows gets to report false.
+	sessionsHasMore, messagesHasMore, sessionTombstonesHasMore, messageTombstonesHasMore := true, true, true, true
 
 	if remaining > 0 {
-		entries, err := c.Repo.GetSessionDiff(ctx, userID, *req.SinceTime, int16(remaining))
+		entries, hasMore, err := c.Repo.GetSessionDiff(ctx, userID, sessionsSinceTS, sessionsSinceUUID, int16(remaining))
 		if err != nil {
 			return nil, stacktrace.Propagate(err, "failed to fetch llmchat session diff")
 		}
 		sessions = entries
+		sessionsHasMore = hasMore
 		remaining -= len(entries)
 	}
 
 	if remaining > 0 {
-		entries, err := c.Repo.GetMessageDiff(ctx, userID, *req.SinceTime, int16(remaining))
+		entries, hasMore, err := c.Repo.GetMessageDiff(ctx, userID, messagesSinceTS, messagesSinceUUID, int16(remaining))
 		if err != nil {
 			return nil, stacktrace.Propagate(err, "failed to fetch llmchat message diff")
 		}
 		messages = entries
+		messagesHasMore = hasMore
 		remaining -= len(entries)
 	}
 
 	if remaining > 0 {
-		entries, err := c.Repo.GetSessionTombstones(ctx, userID, *req.SinceTime, int16(remaining))
+		entries, hasMore, err := c.Repo.GetSessionTombstones(ctx, userID, sessionTombstonesSinceTS, sessionTombstonesSinceUUID, int16(remaining))
 		if err != nil {
 			return nil, stacktrace.Propagate(err, "failed to fetch llmchat session tombstones")
 		}
 		sessionTombstones = entries
+		sessionTombstonesHasMore = hasMore
 		remaining -= len(entries)
 	}
 
 	if remaining > 0 {
-		entries, err := c.Repo.GetMessageTombstones(ctx, userID, *req.SinceTime, int16(remaining))
+		entries, hasMore, err := c.Repo.GetMessageTombstones(ctx, userID, messageTombstonesSinceTS, messageTombstonesSinceUUID, int16(remaining))
 		if err != nil {
 			return nil, stacktrace.Propagate(err, "failed to fetch llmchat message tombstones")
 		}
 		messageTombstones = entries
-	}
-
-	serverTime := time.Now().UnixMicro()
-	maxTimestamp := maxDiffTimestamp(sessions, messages, sessionTombstones, messageTombstones)
-	candidate := maxTimestamp + 1
-	if candidate < serverTime {
-		candidate = serverTime
+		messageTombstonesHasMore = hasMore
 	}
 
 	response := model.GetDiffResponse{
@@ -150,7 +188,14 @@ func (c *Controller) GetDiff(ctx *gin.Context, req model.GetDiffRequest) (*model
 			Sessions: sessionTombstones,
 			Messages: messageTombstones,
 		},
-		Timestamp: candidate,
+		SessionsCursor:           nextDiffCursor(req.SessionsCursor, sessionsSinceTS, sessionsSinceUUID, sessions, func(e model.SessionDiffEntry) (int64, string) { return e.UpdatedAt, e.SessionUUID }),
+		SessionsHasMore:          sessionsHasMore,
+		MessagesCursor:           nextDiffCursor(req.MessagesCursor, messagesSinceTS, messagesSinceUUID, messages, func(e model.MessageDiffEntry) (int64, string) { return e.UpdatedAt, e.MessageUUID }),
+		MessagesHasMore:          messagesHasMore,
+		SessionTombstonesCursor:  nextDiffCursor(req.SessionTombstonesCursor, sessionTombstonesSinceTS, sessionTombstonesSinceUUID, sessionTombstones, func(e model.SessionTombstone) (int64, string) { return e.DeletedAt, e.SessionUUID }),
+		SessionTombstonesHasMore: sessionTombstonesHasMore,
+		MessageTombstonesCursor:  nextDiffCursor(req.MessageTombstonesCursor, messageTombstonesSinceTS, messageTombstonesSinceUUID, messageTombstones, func(e model.MessageTombstone) (int64, string) { return e.DeletedAt, e.MessageUUID }),
+		MessageTombstonesHasMore: messageTombstonesHasMore,
 	}
 	return &response, nil
 }
@@ -190,33 +235,3 @@ func (c *Controller) setKeyCache(userID int64) {
 	}
 	c.KeyCache.SetDefault(c.keyCacheKey(userID), true)
 }
-
-func maxDiffTimestamp(
-	sessions []model.SessionDiffEntry,
-	messages []model.MessageDiffEntry,
-	sessionTombstones []model.SessionTombstone,
-	messageTombstones []model.MessageTombstone,
-) int64 {
-	var max int64
-	for _, entry := range sessions {
-		if entry.UpdatedAt > max {
-			max = entry.UpdatedAt
-		}
-	}
-	for _, entry := range messages {
-		if entry.UpdatedAt > max {
-			max = entry.UpdatedAt
-		}
-	}
-	for _, entry := range sessionTombstones {
-		if entry.DeletedAt > max {
-			max = entry.DeletedAt
-		}
-	}
-	for _, entry := range messageTombstones {
-		if entry.DeletedAt > max {
-			max = entry.DeletedAt
-		}
-	}
-	return max
-}
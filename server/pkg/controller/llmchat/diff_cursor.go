@@ -0,0 +1,66 @@
+package llmchat
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ente-io/stacktrace"
+)
+
+// decodeDiffCursorOrSinceTime decodes an opaque per-stream diff cursor into
+// the (updatedAt, uuid) keyset tiebreaker GetDiff's repository queries page
+// on. An empty cursor (a stream's first page) falls back to sinceTime with
+// an empty uuid, which sorts before every real uuid at that timestamp.
+func decodeDiffCursorOrSinceTime(cursor *string, sinceTime *int64) (updatedAt int64, uuid string, err error) {
+	if cursor == nil || *cursor == "" {
+		if sinceTime != nil {
+			updatedAt = *sinceTime
+		}
+		return updatedAt, "", nil
+	}
+	return decodeDiffCursor(*cursor)
+}
+
+// encodeDiffCursor packs a stream's (updatedAt, uuid) keyset position into
+// an opaque, URL-safe string.
+func encodeDiffCursor(updatedAt int64, uuid string) string {
+	raw := fmt.Sprintf("%d:%s", updatedAt, uuid)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeDiffCursor(cursor string) (updatedAt int64, uuid string, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", stacktrace.Propagate(err, "malformed diff cursor")
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return 0, "", stacktrace.Propagate(fmt.Errorf("malformed diff cursor"), "")
+	}
+	updatedAt, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", stacktrace.Propagate(err, "malformed diff cursor timestamp")
+	}
+	return updatedAt, parts[1], nil
+}
+
+// nextDiffCursor computes the cursor GetDiff should return for a stream:
+// the keyset position of the last entry in this page, so the next request
+// resumes exactly where this one left off. An empty page means nothing
+// advanced: the incoming cursor is echoed back as-is, and without one the
+// sinceTime position is returned as a cursor ("" when sinceTime was 0).
+func nextDiffCursor[T any](prevCursor *string, prevTS int64, prevUUID string, entries []T, keyOf func(T) (int64, string)) string {
+	if len(entries) == 0 {
+		if prevCursor != nil {
+			return *prevCursor
+		}
+		if prevUUID == "" && prevTS == 0 {
+			return ""
+		}
+		return encodeDiffCursor(prevTS, prevUUID)
+	}
+	lastTS, lastUUID := keyOf(entries[len(entries)-1])
+	return encodeDiffCursor(lastTS, lastUUID)
+}
@@ -0,0 +1,128 @@
+package llmchat
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/museum/pkg/utils/auth"
+	entetime "github.com/ente-io/museum/pkg/utils/time"
+	"github.com/ente-io/stacktrace"
+	"github.com/gin-gonic/gin"
+)
+
+// exportArchiveBatchSize bounds how many sessions ExportArchive pulls from
+// the repository per round trip, so one caller's export can't hold a
+// single, unbounded query open.
+const exportArchiveBatchSize = 500
+
+// ExportManifest is the top-level manifest.json entry bundled into every
+// export archive, so a client can inspect what an archive contains without
+// first walking every per-session entry.
+type ExportManifest struct {
+	UserID      int64                   `json:"userID"`
+	GeneratedAt int64                   `json:"generatedAt"`
+	SinceTime   int64                   `json:"sinceTime"`
+	Sessions    []ExportManifestSession `json:"sessions"`
+}
+
+// ExportManifestSession summarizes one session's entry in the archive.
+type ExportManifestSession struct {
+	SessionUUID  string `json:"sessionUUID"`
+	MessageCount int    `json:"messageCount"`
+	UpdatedAt    int64  `json:"updatedAt"`
+}
+
+// exportSessionBundle is the payload written as <sessionUUID>.json inside
+// the archive: the session's own encrypted blob plus every message
+// belonging to it, ordered by creation time, each carrying its
+// ParentMessageUUID so a client can rebuild the conversation tree without
+// re-deriving structure from timestamps.
+type exportSessionBundle struct {
+	Session  model.Session   `json:"session"`
+	Messages []model.Message `json:"messages"`
+}
+
+// ExportArchive streams every session (and its messages) the caller owns,
+// updated since sinceTime, as a ZIP of per-session JSON bundles plus a
+// manifest.json summary. Every entry stays exactly as stored -- encrypted
+// blobs and headers -- so the server never needs plaintext chat content to
+// build the archive.
+func (c *Controller) ExportArchive(ctx *gin.Context, sinceTime int64) (err error) {
+	if err := c.validateKey(ctx); err != nil {
+		return stacktrace.Propagate(err, "failed to validateKey")
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+
+	manifest := ExportManifest{
+		UserID:      userID,
+		GeneratedAt: entetime.Microseconds(),
+		SinceTime:   sinceTime,
+	}
+
+	ctx.Writer.Header().Set("Content-Type", "application/zip")
+	ctx.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="llmchat-export-%d.zip"`, userID))
+	ctx.Writer.WriteHeader(http.StatusOK)
+
+	zipWriter := zip.NewWriter(ctx.Writer)
+	defer func() {
+		if closeErr := zipWriter.Close(); closeErr != nil && err == nil {
+			err = stacktrace.Propagate(closeErr, "failed to finalize export archive")
+		}
+	}()
+
+	cursor, cursorUUID := sinceTime, ""
+	for {
+		sessions, hasMore, listErr := c.Repo.ListSessionsForExport(ctx, userID, cursor, cursorUUID, exportArchiveBatchSize)
+		if listErr != nil {
+			return stacktrace.Propagate(listErr, "failed to list sessions for export")
+		}
+		if len(sessions) == 0 {
+			break
+		}
+
+		for _, session := range sessions {
+			messages, msgErr := c.Repo.ListMessagesForSessionExport(ctx, userID, session.SessionUUID)
+			if msgErr != nil {
+				return stacktrace.Propagate(msgErr, "failed to list messages for export")
+			}
+
+			entryWriter, createErr := zipWriter.Create(session.SessionUUID + ".json")
+			if createErr != nil {
+				return stacktrace.Propagate(createErr, "failed to create archive entry")
+			}
+			bundle := exportSessionBundle{Session: session, Messages: messages}
+			if encErr := json.NewEncoder(entryWriter).Encode(bundle); encErr != nil {
+				return stacktrace.Propagate(encErr, "failed to write archive entry")
+			}
+
+			manifest.Sessions = append(manifest.Sessions, ExportManifestSession{
+				SessionUUID:  session.SessionUUID,
+				MessageCount: len(messages),
+				UpdatedAt:    session.UpdatedAt,
+			})
+		}
+
+		// Advance the keyset cursor past the last row of this page -- not
+		// past the max timestamp seen, which would silently drop any other
+		// session sharing that same updated_at microsecond.
+		last := sessions[len(sessions)-1]
+		cursor, cursorUUID = last.UpdatedAt, last.SessionUUID
+
+		if !hasMore {
+			break
+		}
+	}
+
+	manifestWriter, createErr := zipWriter.Create("manifest.json")
+	if createErr != nil {
+		return stacktrace.Propagate(createErr, "failed to create manifest entry")
+	}
+	if encErr := json.NewEncoder(manifestWriter).Encode(manifest); encErr != nil {
+		return stacktrace.Propagate(encErr, "failed to write manifest entry")
+	}
+
+	return nil
+}
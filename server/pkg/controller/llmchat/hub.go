@@ -0,0 +1,79 @@
+package llmchat
+
+import "sync"
+
+// Event is a single push notification fanned out to a user's subscribed
+// connections: a new/updated session or message, or a tombstone for either.
+type Event struct {
+	Type    string      `json:"type"` // "session", "message", "session_tombstone", "message_tombstone"
+	Payload interface{} `json:"payload"`
+}
+
+// hubSubscriberBuffer bounds how many undelivered events a single slow
+// connection can accumulate before Publish drops its oldest event rather
+// than blocking every other subscriber on that user.
+const hubSubscriberBuffer = 32
+
+// Hub fans out llmchat events to every connection currently subscribed for
+// a user, so all of that user's devices see a write live instead of
+// polling GetDiff. It holds no reference to the database itself --
+// ListenForNotifications is what turns Postgres NOTIFY payloads into
+// Publish calls.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan Event]struct{}
+}
+
+// NewHub creates an empty Hub.
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[int64]map[chan Event]struct{})}
+}
+
+// Subscribe registers a new connection for userID and returns its event
+// channel plus an unsubscribe func the caller must invoke (typically via
+// defer) once the connection closes.
+func (h *Hub) Subscribe(userID int64) (<-chan Event, func()) {
+	ch := make(chan Event, hubSubscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[userID], ch)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+		close(ch)
+	}
+	return ch, unsubscribe
+}
+
+// Publish fans event out to every connection currently subscribed for
+// userID. A subscriber whose buffer is already full has its oldest event
+// dropped rather than stalling every other subscriber on the same user --
+// a reconnecting client is expected to fall back to GetDiff to fill any
+// gap this leaves.
+func (h *Hub) Publish(userID int64, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- event:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package llmchat
+
+import (
+	"github.com/ente-io/museum/ente"
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/museum/pkg/repo"
+	"github.com/ente-io/museum/pkg/utils/auth"
+	"github.com/ente-io/stacktrace"
+	"github.com/gin-gonic/gin"
+)
+
+// AttachFile links fileID, an existing file from the caller's Ente library,
+// to messageUUID. fileID's key is re-wrapped under a per-attachment secret
+// the client derives itself -- never the llmchat session key -- so a
+// message referencing a photo doesn't hand out access to every other
+// message in the conversation. The upload itself is unaffected; this only
+// records the reference.
+func (c *Controller) AttachFile(ctx *gin.Context, messageUUID string, fileID int64, encryptedKey string, keyDecryptionNonce string) (*model.Attachment, error) {
+	if err := c.validateKey(ctx); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to validateKey")
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+	if err := c.verifyFileOwnership(ctx, userID, fileID); err != nil {
+		return nil, stacktrace.Propagate(err, "User does not own file")
+	}
+
+	res, err := c.Repo.AttachFile(ctx, userID, messageUUID, fileID, encryptedKey, keyDecryptionNonce)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to attach file to llmchat message")
+	}
+	return &res, nil
+}
+
+// DetachFile removes a single attachment from messageUUID. Other files
+// still linked to the message are unaffected.
+func (c *Controller) DetachFile(ctx *gin.Context, messageUUID string, fileID int64) error {
+	if err := c.validateKey(ctx); err != nil {
+		return stacktrace.Propagate(err, "failed to validateKey")
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+	if err := c.Repo.DetachFile(ctx, userID, messageUUID, fileID); err != nil {
+		return stacktrace.Propagate(err, "failed to detach file from llmchat message")
+	}
+	return nil
+}
+
+// ListAttachments returns every file currently linked to messageUUID.
+func (c *Controller) ListAttachments(ctx *gin.Context, messageUUID string) ([]model.Attachment, error) {
+	if err := c.validateKey(ctx); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to validateKey")
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+	res, err := c.Repo.ListAttachments(ctx, userID, messageUUID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to list llmchat message attachments")
+	}
+	return res, nil
+}
+
+// verifyFileOwnership confirms userID owns fileID before it can be
+// referenced from a chat message, mirroring CollectionController's
+// verifyOwnership check for collections.
+func (c *Controller) verifyFileOwnership(ctx *gin.Context, userID int64, fileID int64) error {
+	if c.FileRepo == nil {
+		return stacktrace.Propagate(ente.ErrNotImplemented, "file attachments are not enabled")
+	}
+	ownerID, err := c.FileRepo.GetOwnerID(ctx, fileID)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to resolve file owner")
+	}
+	if ownerID != userID {
+		return stacktrace.Propagate(ente.ErrPermissionDenied, "file does not belong to user")
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package llmchat
+
+import (
+	"fmt"
+
+	"github.com/ente-io/museum/ente"
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/museum/pkg/utils/auth"
+	entetime "github.com/ente-io/museum/pkg/utils/time"
+	"github.com/ente-io/stacktrace"
+	"github.com/gin-gonic/gin"
+)
+
+// upsertMessagesBatchLimit bounds how many messages a single UpsertMessages
+// call can reconcile, so a client replaying an offline queue can't hold the
+// batch transaction open indefinitely.
+const upsertMessagesBatchLimit = 200
+
+// UpsertMessages reconciles many offline-authored messages in one round
+// trip instead of the per-message cost of UpsertMessage. Results are
+// returned in request order, one per input message, so a client can tell
+// exactly which of its batch landed; ServerTime lets it advance its sync
+// cursor atomically even when every row in the batch failed.
+func (c *Controller) UpsertMessages(ctx *gin.Context, reqs []model.UpsertMessageRequest) (*model.UpsertMessagesBatchResponse, error) {
+	if err := c.validateKey(ctx); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to validateKey")
+	}
+	if len(reqs) > upsertMessagesBatchLimit {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, fmt.Sprintf("batch exceeds limit of %d messages", upsertMessagesBatchLimit))
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+
+	messages, errs := c.Repo.UpsertMessagesBatch(ctx, userID, reqs)
+
+	results := make([]model.UpsertMessageBatchResult, len(reqs))
+	for i := range reqs {
+		if errs[i] != nil {
+			results[i] = model.UpsertMessageBatchResult{Error: errs[i].Error()}
+			continue
+		}
+		message := messages[i]
+		results[i] = model.UpsertMessageBatchResult{Message: &message}
+	}
+
+	return &model.UpsertMessagesBatchResponse{
+		Results:    results,
+		ServerTime: entetime.Microseconds(),
+	}, nil
+}
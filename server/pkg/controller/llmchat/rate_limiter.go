@@ -0,0 +1,38 @@
+package llmchat
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a minimal token bucket: burst tokens refill fully every
+// window. It's deliberately simpler than a smooth leaky-bucket since
+// Subscribe only needs a coarse cap on how many events reach a single slow
+// connection, not fairness across windows.
+type rateLimiter struct {
+	mu       sync.Mutex
+	burst    int
+	window   time.Duration
+	tokens   int
+	resetsAt time.Time
+}
+
+func newRateLimiter(burst int, window time.Duration) *rateLimiter {
+	return &rateLimiter{burst: burst, window: window, tokens: burst, resetsAt: time.Now().Add(window)}
+}
+
+// Allow reports whether the caller may proceed, consuming a token if so.
+func (l *rateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.After(l.resetsAt) {
+		l.tokens = l.burst
+		l.resetsAt = now.Add(l.window)
+	}
+	if l.tokens <= 0 {
+		return false
+	}
+	l.tokens--
+	return true
+}
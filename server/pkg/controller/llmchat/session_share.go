@@ -0,0 +1,151 @@
+package llmchat
+
+import (
+	"crypto/subtle"
+
+	"github.com/ente-io/museum/ente"
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/museum/pkg/utils/auth"
+	entetime "github.com/ente-io/museum/pkg/utils/time"
+	"github.com/ente-io/stacktrace"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionShareParams are the public, unauthenticated parameters a viewer
+// needs to derive the same secret the owner used when wrapping the share's
+// session key, mirroring how a collection's PublicURL already exposes its
+// own Nonce/OpsLimit/MemLimit before a JWT exists.
+type SessionShareParams struct {
+	PasswordEnabled bool
+	Nonce           string
+	OpsLimit        int64
+	MemLimit        int64
+	ReadOnly        bool
+}
+
+// ResolveSessionShareResponse is returned once a viewer has proven they hold
+// the share's secret (or the share needs no password at all): the share's
+// own wrapped session key, and the session plus its messages, exactly as
+// stored -- the server never needs plaintext chat content to resolve one.
+type ResolveSessionShareResponse struct {
+	EncryptedKey       string
+	KeyDecryptionNonce string
+	ReadOnly           bool
+	Session            model.Session
+	Messages           []model.Message
+}
+
+// CreateSessionShare lets the caller hand out a link to a single session
+// without exposing their whole llmchat key: sessionKey is wrapped under a
+// share-specific secret the viewer derives locally, exactly like
+// EncryptedKey/KeyDecryptionNonce on a collection's PublicURL.
+func (c *Controller) CreateSessionShare(ctx *gin.Context, req model.CreateSessionShareRequest) (*model.SessionShare, error) {
+	if err := c.validateKey(ctx); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to validateKey")
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+	if _, err := c.Repo.GetSessionByUUID(ctx, userID, req.SessionUUID); err != nil {
+		return nil, stacktrace.Propagate(err, "User does not own session")
+	}
+	res, err := c.Repo.CreateSessionShare(ctx, userID, req)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create llmchat session share")
+	}
+	return &res, nil
+}
+
+// UpdateSessionShare lets the owner change a share's expiry, device limit,
+// read-only flag, or password without rotating its token -- existing
+// recipients keep the same link.
+func (c *Controller) UpdateSessionShare(ctx *gin.Context, req model.UpdateSessionShareRequest) (*model.SessionShare, error) {
+	if err := c.validateKey(ctx); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to validateKey")
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+	res, err := c.Repo.UpdateSessionShare(ctx, userID, req)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to update llmchat session share")
+	}
+	return &res, nil
+}
+
+// RevokeSessionShare invalidates shareToken. Other shares on the same
+// session, and the session itself, are unaffected.
+func (c *Controller) RevokeSessionShare(ctx *gin.Context, shareToken string) error {
+	if err := c.validateKey(ctx); err != nil {
+		return stacktrace.Propagate(err, "failed to validateKey")
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+	if err := c.Repo.RevokeSessionShare(ctx, userID, shareToken); err != nil {
+		return stacktrace.Propagate(err, "failed to revoke llmchat session share")
+	}
+	return nil
+}
+
+// GetSessionShareParams looks up the public parameters for shareToken, so a
+// viewer can derive a password proof (if one is needed) before resolving the
+// share itself.
+func (c *Controller) GetSessionShareParams(ctx *gin.Context, shareToken string) (res *SessionShareParams, err error) {
+	share, err := c.Repo.GetSessionShareByToken(ctx, shareToken)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to fetch llmchat session share")
+	}
+	if share.ValidTill != 0 && share.ValidTill < entetime.Microseconds() {
+		return nil, stacktrace.Propagate(ente.ErrPermissionDenied, "session share has expired")
+	}
+	return &SessionShareParams{
+		PasswordEnabled: share.PasswordEnabled,
+		Nonce:           share.Nonce,
+		OpsLimit:        share.OpsLimit,
+		MemLimit:        share.MemLimit,
+		ReadOnly:        share.ReadOnly,
+	}, nil
+}
+
+// ResolveSessionShare is the public, unauthenticated counterpart to
+// validateKey: it looks up shareToken, checks proof against the share's
+// verifier when PasswordEnabled, enforces the share's DeviceLimit against
+// deviceID, and on success returns the share's wrapped session key plus the
+// session's encrypted messages exactly as stored. deviceID is a stable
+// identifier the client generates and persists locally -- the same value
+// must be sent on every resolve from that device so it keeps counting as
+// the one slot instead of a fresh one each time.
+func (c *Controller) ResolveSessionShare(ctx *gin.Context, shareToken string, proof string, deviceID string) (res *ResolveSessionShareResponse, err error) {
+	if deviceID == "" {
+		return nil, stacktrace.Propagate(ente.ErrBadRequest, "missing device id")
+	}
+	share, err := c.Repo.GetSessionShareByToken(ctx, shareToken)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to fetch llmchat session share")
+	}
+	if share.ValidTill != 0 && share.ValidTill < entetime.Microseconds() {
+		return nil, stacktrace.Propagate(ente.ErrPermissionDenied, "session share has expired")
+	}
+	if share.PasswordEnabled && subtle.ConstantTimeCompare([]byte(proof), []byte(share.VerifierHash)) != 1 {
+		return nil, stacktrace.Propagate(ente.ErrPermissionDenied, "session share verification failed")
+	}
+	allowed, err := c.Repo.RecordSessionShareDevice(ctx, shareToken, deviceID, share.DeviceLimit)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to record llmchat session share device")
+	}
+	if !allowed {
+		return nil, stacktrace.Propagate(ente.ErrPermissionDenied, "session share device limit reached")
+	}
+
+	session, err := c.Repo.GetSessionForShare(ctx, share.OwnerID, share.SessionUUID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to fetch shared session")
+	}
+	messages, err := c.Repo.ListMessagesForSessionExport(ctx, share.OwnerID, share.SessionUUID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to fetch shared session messages")
+	}
+
+	return &ResolveSessionShareResponse{
+		EncryptedKey:       share.EncryptedKey,
+		KeyDecryptionNonce: share.KeyDecryptionNonce,
+		ReadOnly:           share.ReadOnly,
+		Session:            session,
+		Messages:           messages,
+	}, nil
+}
@@ -0,0 +1,141 @@
+package llmchat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ente-io/museum/ente"
+	"github.com/ente-io/museum/pkg/utils/auth"
+	"github.com/ente-io/stacktrace"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// subscribeHeartbeatInterval keeps an idle SSE connection (and any
+	// intermediate proxy) from timing out between real events.
+	subscribeHeartbeatInterval = 30 * time.Second
+
+	// subscribeRateLimitBurst/subscribeRateLimitWindow bound how many
+	// events a single connection is sent per window (a fixed window that
+	// restarts on the first event after the previous one ends). A
+	// particularly chatty hub (a bulk import, say) can't flood a slow
+	// connection -- events beyond the cap are dropped, since a client can
+	// always fall back to GetDiff to catch up on reconnect.
+	subscribeRateLimitBurst  = 20
+	subscribeRateLimitWindow = time.Second
+
+	// llmchatEventsChannel is the Postgres NOTIFY channel an AFTER
+	// INSERT/UPDATE trigger on ensu_chat_sessions and ensu_chat_messages
+	// publishes to.
+	llmchatEventsChannel = "llmchat_events"
+)
+
+// Subscribe upgrades the request to a long-lived SSE stream and pushes the
+// authenticated user's llmchat events (new/updated sessions, messages, and
+// both tombstone kinds) as they happen. A client reconnecting after a gap
+// is expected to first call GetDiff with its last-seen cursors to fill
+// whatever it missed while disconnected, then Subscribe to stay current --
+// the hub makes no delivery guarantee across a dropped connection.
+func (c *Controller) Subscribe(ctx *gin.Context) (err error) {
+	if err := c.validateKey(ctx); err != nil {
+		return stacktrace.Propagate(err, "failed to validateKey")
+	}
+	if c.Hub == nil {
+		return stacktrace.Propagate(ente.ErrNotImplemented, "live subscriptions are not enabled")
+	}
+	flusher, ok := ctx.Writer.(http.Flusher)
+	if !ok {
+		return stacktrace.Propagate(fmt.Errorf("response writer does not support streaming"), "")
+	}
+	userID := auth.GetUserID(ctx.Request.Header)
+
+	events, unsubscribe := c.Hub.Subscribe(userID)
+	defer unsubscribe()
+
+	ctx.Writer.Header().Set("Content-Type", "text/event-stream")
+	ctx.Writer.Header().Set("Cache-Control", "no-cache")
+	ctx.Writer.Header().Set("Connection", "keep-alive")
+	ctx.Writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	limiter := newRateLimiter(subscribeRateLimitBurst, subscribeRateLimitWindow)
+	heartbeat := time.NewTicker(subscribeHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return nil
+		case <-heartbeat.C:
+			if _, writeErr := fmt.Fprint(ctx.Writer, ": heartbeat\n\n"); writeErr != nil {
+				return nil
+			}
+			flusher.Flush()
+		case event, open := <-events:
+			if !open {
+				return nil
+			}
+			if !limiter.Allow() {
+				continue
+			}
+			data, marshalErr := json.Marshal(event)
+			if marshalErr != nil {
+				log.WithError(marshalErr).Error("failed to marshal llmchat event")
+				continue
+			}
+			if _, writeErr := fmt.Fprintf(ctx.Writer, "event: %s\ndata: %s\n\n", event.Type, data); writeErr != nil {
+				return nil
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// notifyPayload is the JSON body of each llmchatEventsChannel NOTIFY,
+// written by the ensu_chat_sessions/ensu_chat_messages trigger.
+type notifyPayload struct {
+	UserID  int64       `json:"userID"`
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// ListenForNotifications subscribes to Postgres NOTIFY traffic on
+// llmchatEventsChannel and republishes every payload through the hub. It
+// runs until ctx is done or listener's notification channel is closed;
+// pq.Listener handles reconnecting its own underlying connection on
+// transient failures.
+func (c *Controller) ListenForNotifications(ctx context.Context, listener *pq.Listener) error {
+	if c.Hub == nil {
+		return stacktrace.Propagate(ente.ErrNotImplemented, "live subscriptions are not enabled")
+	}
+	if err := listener.Listen(llmchatEventsChannel); err != nil {
+		return stacktrace.Propagate(err, "failed to listen on "+llmchatEventsChannel)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification, open := <-listener.Notify:
+			if !open {
+				return nil
+			}
+			if notification == nil {
+				// pq.Listener sends a nil notification after it silently
+				// reconnects; nothing was missed that GetDiff can't cover.
+				continue
+			}
+			var payload notifyPayload
+			if err := json.Unmarshal([]byte(notification.Extra), &payload); err != nil {
+				log.WithError(err).Error("failed to decode llmchat NOTIFY payload")
+				continue
+			}
+			c.Hub.Publish(payload.UserID, Event{Type: payload.Type, Payload: payload.Payload})
+		}
+	}
+}
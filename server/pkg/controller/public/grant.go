@@ -0,0 +1,140 @@
+package public
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"github.com/ente-io/museum/ente"
+	"github.com/ente-io/museum/pkg/repo"
+	"github.com/ente-io/museum/pkg/utils/auth"
+	"github.com/ente-io/stacktrace"
+)
+
+// grantJWTTTL bounds how long a resolved grant's JWT authorizes further
+// public-collection requests, matching the lifetime already used for
+// password-verified sessions.
+const grantJWTTTL = 24 * time.Hour
+
+// ResolveGrantResponse is returned once a grantee has proven ownership of
+// their grant: a JWT authorizing subsequent public-collection requests, and
+// the grantee's own encrypted collection key, which the client decrypts
+// locally using the same secret it used to compute proof.
+type ResolveGrantResponse struct {
+	JWTToken           string
+	EncryptedKey       string
+	KeyDecryptionNonce string
+	Nonce              string
+	OpsLimit           int64
+	MemLimit           int64
+}
+
+// GrantController manages a collection's public-link ACL: a list of
+// per-grantee wrapped collection keys layered on top of the link's single
+// PublicURL, so an owner can revoke one recipient without invalidating the
+// link for everyone else or rotating the shared password.
+type GrantController struct {
+	GrantRepo      *repo.PublicLinkGrantRepository
+	CollectionRepo *repo.CollectionRepository
+}
+
+// AddGrant upserts the grant for granteeID on collectionID. encryptedKey is
+// the collection key encrypted under a secret the grantee derives locally
+// (from their password, or an ECDH shared secret from their public key);
+// verifierHash is a value the server can compare a future proof against
+// without being able to derive the wrapping secret from it.
+func (g *GrantController) AddGrant(ctx context.Context, collectionID int64, granteeID string, authMethod repo.GranteeAuthMethod, encryptedKey, keyDecryptionNonce, verifierHash, nonce string, opsLimit, memLimit int64, publicKey *string) (repo.PublicLinkGrant, error) {
+	grant, err := g.GrantRepo.AddGrant(ctx, collectionID, granteeID, authMethod, encryptedKey, keyDecryptionNonce, verifierHash, nonce, opsLimit, memLimit, publicKey)
+	if err != nil {
+		return grant, stacktrace.Propagate(err, "failed to add public link grant")
+	}
+	return grant, nil
+}
+
+// ListGrants returns every grantee currently provisioned on collectionID.
+func (g *GrantController) ListGrants(ctx context.Context, collectionID int64) ([]repo.PublicLinkGrant, error) {
+	grants, err := g.GrantRepo.ListGrants(ctx, collectionID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to list public link grants")
+	}
+	return grants, nil
+}
+
+// RevokeGrant removes granteeID's access to collectionID. Other grantees,
+// and the collection's shared PublicURL password if one exists, are
+// unaffected.
+func (g *GrantController) RevokeGrant(ctx context.Context, collectionID int64, granteeID string) error {
+	if err := g.GrantRepo.RevokeGrant(ctx, collectionID, granteeID); err != nil {
+		return stacktrace.Propagate(err, "failed to revoke public link grant")
+	}
+	return nil
+}
+
+// GrantParams are the public, unauthenticated parameters a grantee needs to
+// derive the same secret the owner used when wrapping their collection key
+// (e.g. the Argon2 salt and limits for a password-based grant). They carry
+// no information that would let anyone other than the grantee compute the
+// proof ResolveGrant checks.
+type GrantParams struct {
+	AuthMethod repo.GranteeAuthMethod
+	Nonce      string
+	OpsLimit   int64
+	MemLimit   int64
+}
+
+// GetGrantParams looks up the public parameters for granteeHint under
+// accessToken's collection, mirroring how a link's single PublicURL already
+// exposes its Nonce/OpsLimit/MemLimit unauthenticated so a client can derive
+// a password proof before it has a JWT.
+func (g *GrantController) GetGrantParams(ctx context.Context, accessToken string, granteeHint string) (res *GrantParams, err error) {
+	collectionID, err := g.CollectionRepo.GetCollectionIDByPublicToken(ctx, accessToken)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to resolve access token")
+	}
+
+	grant, err := g.GrantRepo.GetGrant(ctx, collectionID, granteeHint)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to fetch public link grant")
+	}
+
+	return &GrantParams{
+		AuthMethod: grant.AuthMethod,
+		Nonce:      grant.Nonce,
+		OpsLimit:   grant.OpsLimit,
+		MemLimit:   grant.MemLimit,
+	}, nil
+}
+
+// ResolveGrant is the ACL-aware counterpart to verifying a link's single
+// shared password: it looks up the grant identified by granteeHint under
+// accessToken's collection, checks proof against the grant's verifier, and
+// on success returns a JWT plus that grantee's own encrypted collection key.
+func (g *GrantController) ResolveGrant(ctx context.Context, accessToken string, granteeHint string, proof string) (res *ResolveGrantResponse, err error) {
+	collectionID, err := g.CollectionRepo.GetCollectionIDByPublicToken(ctx, accessToken)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to resolve access token")
+	}
+
+	grant, err := g.GrantRepo.GetGrant(ctx, collectionID, granteeHint)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to fetch public link grant")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(proof), []byte(grant.VerifierHash)) != 1 {
+		return nil, stacktrace.Propagate(ente.ErrPermissionDenied, "grant verification failed")
+	}
+
+	token, err := auth.SignPublicCollectionJWT(collectionID, grant.GranteeID, grantJWTTTL)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to sign grant jwt")
+	}
+
+	return &ResolveGrantResponse{
+		JWTToken:           token,
+		EncryptedKey:       grant.EncryptedKey,
+		KeyDecryptionNonce: grant.KeyDecryptionNonce,
+		Nonce:              grant.Nonce,
+		OpsLimit:           grant.OpsLimit,
+		MemLimit:           grant.MemLimit,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package ensuchat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ente-io/museum/ente"
+	model "github.com/ente-io/museum/ente/ensuchat"
+	"github.com/ente-io/stacktrace"
+)
+
+// lowPreKeyThreshold is surfaced in GetKey so clients know when to top up
+// their one-time prekey supply before it runs dry.
+const lowPreKeyThreshold = 20
+
+// UpsertSignedPreKey replaces the caller's medium-term signed prekey. Only one
+// signed prekey is kept per user; re-uploading rotates it.
+func (r *Repository) UpsertSignedPreKey(ctx context.Context, userID int64, req model.UpsertSignedPreKeyRequest) (model.SignedPreKey, error) {
+	row := r.DB.QueryRowContext(ctx, `INSERT INTO ensu_chat_signed_prekeys(
+		user_id,
+		key_id,
+		public_key,
+		signature
+	) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id) DO UPDATE
+		SET key_id = EXCLUDED.key_id,
+			public_key = EXCLUDED.public_key,
+			signature = EXCLUDED.signature,
+			created_at = now_utc_micro_seconds()
+	RETURNING key_id, public_key, signature, created_at`,
+		userID,
+		req.KeyID,
+		req.PublicKey,
+		req.Signature,
+	)
+
+	var result model.SignedPreKey
+	if err := row.Scan(&result.KeyID, &result.PublicKey, &result.Signature, &result.CreatedAt); err != nil {
+		return result, stacktrace.Propagate(err, "failed to upsert ensu chat signed prekey")
+	}
+	return result, nil
+}
+
+// UploadOneTimePreKeys adds a batch of ephemeral one-time prekeys that can
+// each be handed out at most once via FetchPreKeyBundle.
+func (r *Repository) UploadOneTimePreKeys(ctx context.Context, userID int64, keys []model.OneTimePreKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO ensuchat_prekeys(
+		user_id,
+		key_id,
+		public_key
+	) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, key_id) DO NOTHING`)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to prepare one-time prekey insert")
+	}
+	defer stmt.Close() // nolint: errcheck
+
+	for _, key := range keys {
+		if _, err = stmt.ExecContext(ctx, userID, key.KeyID, key.PublicKey); err != nil {
+			return stacktrace.Propagate(err, "failed to insert one-time prekey")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return stacktrace.Propagate(err, "failed to commit one-time prekey upload")
+	}
+	return nil
+}
+
+// RemainingOneTimePreKeys returns the count of unconsumed one-time prekeys a
+// user still has available.
+func (r *Repository) RemainingOneTimePreKeys(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	row := r.DB.QueryRowContext(ctx, `SELECT count(*)
+		FROM ensuchat_prekeys
+		WHERE user_id = $1 AND consumed_at IS NULL`,
+		userID,
+	)
+	if err := row.Scan(&count); err != nil {
+		return 0, stacktrace.Propagate(err, "failed to count remaining one-time prekeys")
+	}
+	return count, nil
+}
+
+// FetchPreKeyBundle atomically pops one of peerUserID's one-time prekeys (if
+// any remain) and returns it alongside their identity key and signed prekey,
+// following the X3DH bundle shape. The pop is guarded by
+// SELECT ... FOR UPDATE SKIP LOCKED so two concurrent fetches for the same
+// peer can't be handed the same one-time key.
+func (r *Repository) FetchPreKeyBundle(ctx context.Context, peerUserID int64) (model.PreKeyBundle, error) {
+	var bundle model.PreKeyBundle
+
+	identityRow := r.DB.QueryRowContext(ctx, `SELECT public_key
+		FROM ensu_chat_keys
+		WHERE user_id = $1`,
+		peerUserID,
+	)
+	if err := identityRow.Scan(&bundle.IdentityKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return bundle, stacktrace.Propagate(&ente.ErrNotFoundError, "peer has no ensu chat identity key")
+		}
+		return bundle, stacktrace.Propagate(err, "failed to fetch peer identity key")
+	}
+
+	signedRow := r.DB.QueryRowContext(ctx, `SELECT key_id, public_key, signature, created_at
+		FROM ensu_chat_signed_prekeys
+		WHERE user_id = $1`,
+		peerUserID,
+	)
+	if err := signedRow.Scan(
+		&bundle.SignedPreKey.KeyID,
+		&bundle.SignedPreKey.PublicKey,
+		&bundle.SignedPreKey.Signature,
+		&bundle.SignedPreKey.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return bundle, stacktrace.Propagate(&ente.ErrNotFoundError, "peer has no signed prekey")
+		}
+		return bundle, stacktrace.Propagate(err, "failed to fetch peer signed prekey")
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return bundle, stacktrace.Propagate(err, "failed to begin transaction")
+	}
+	defer tx.Rollback() // nolint: errcheck
+
+	var keyID, publicKey string
+	row := tx.QueryRowContext(ctx, `SELECT key_id, public_key
+		FROM ensuchat_prekeys
+		WHERE user_id = $1 AND consumed_at IS NULL
+		ORDER BY key_id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`,
+		peerUserID,
+	)
+	switch err := row.Scan(&keyID, &publicKey); {
+	case err == nil:
+		if _, err = tx.ExecContext(ctx, `UPDATE ensuchat_prekeys
+			SET consumed_at = now_utc_micro_seconds()
+			WHERE user_id = $1 AND key_id = $2`,
+			peerUserID, keyID,
+		); err != nil {
+			return bundle, stacktrace.Propagate(err, "failed to mark one-time prekey consumed")
+		}
+		bundle.OneTimePreKey = &model.OneTimePreKey{KeyID: keyID, PublicKey: publicKey}
+	case errors.Is(err, sql.ErrNoRows):
+		// No one-time prekeys left; the bundle is still usable without one.
+	default:
+		return bundle, stacktrace.Propagate(err, "failed to pop one-time prekey")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return bundle, stacktrace.Propagate(err, "failed to commit prekey bundle fetch")
+	}
+	return bundle, nil
+}
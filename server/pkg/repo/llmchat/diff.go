@@ -0,0 +1,237 @@
+package llmchat
+
+import (
+	"context"
+	"database/sql"
+
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+// GetSessionDiff returns up to limit sessions updated strictly after the
+// (sinceTime, sinceUUID) keyset position, plus whether more rows exist
+// beyond this page. Pairing the timestamp with a tiebreaker uuid (instead
+// of paging on updated_at alone) means two sessions sharing an updated_at
+// microsecond under a batch upsert can't have one of them skipped when the
+// caller's next request starts from "maxTimestamp+1".
+func (r *Repository) GetSessionDiff(ctx context.Context, userID int64, sinceTime int64, sinceUUID string, limit int16) ([]model.SessionDiffEntry, bool, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT session_uuid, encrypted_data, header, created_at, updated_at
+		FROM ensu_chat_sessions
+		WHERE user_id = $1 AND is_deleted = FALSE AND (updated_at, session_uuid) > ($2, $3)
+		ORDER BY updated_at, session_uuid
+		LIMIT $4`,
+		userID,
+		sinceTime,
+		sinceUUID,
+		limit+1,
+	)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "failed to query llmchat session diff")
+	}
+	entries, err := convertRowsToSessionDiffEntries(rows)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "")
+	}
+	return truncateSessionDiffEntries(entries, limit)
+}
+
+// GetMessageDiff is GetSessionDiff's counterpart for ensu_chat_messages.
+func (r *Repository) GetMessageDiff(ctx context.Context, userID int64, sinceTime int64, sinceUUID string, limit int16) ([]model.MessageDiffEntry, bool, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT message_uuid, session_uuid, parent_message_uuid, encrypted_data, header, created_at, updated_at
+		FROM ensu_chat_messages
+		WHERE user_id = $1 AND is_deleted = FALSE AND (updated_at, message_uuid) > ($2, $3)
+		ORDER BY updated_at, message_uuid
+		LIMIT $4`,
+		userID,
+		sinceTime,
+		sinceUUID,
+		limit+1,
+	)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "failed to query llmchat message diff")
+	}
+	entries, err := convertRowsToMessageDiffEntries(rows)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "")
+	}
+	return truncateMessageDiffEntries(entries, limit)
+}
+
+// GetSessionTombstones is GetSessionDiff's tombstone counterpart.
+func (r *Repository) GetSessionTombstones(ctx context.Context, userID int64, sinceTime int64, sinceUUID string, limit int16) ([]model.SessionTombstone, bool, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT session_uuid, updated_at
+		FROM ensu_chat_sessions
+		WHERE user_id = $1 AND is_deleted = TRUE AND (updated_at, session_uuid) > ($2, $3)
+		ORDER BY updated_at, session_uuid
+		LIMIT $4`,
+		userID,
+		sinceTime,
+		sinceUUID,
+		limit+1,
+	)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "failed to query llmchat session tombstones")
+	}
+	entries, err := convertRowsToSessionTombstones(rows)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "")
+	}
+	return truncateSessionTombstones(entries, limit)
+}
+
+// GetMessageTombstones is GetMessageDiff's tombstone counterpart.
+func (r *Repository) GetMessageTombstones(ctx context.Context, userID int64, sinceTime int64, sinceUUID string, limit int16) ([]model.MessageTombstone, bool, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT message_uuid, updated_at
+		FROM ensu_chat_messages
+		WHERE user_id = $1 AND is_deleted = TRUE AND (updated_at, message_uuid) > ($2, $3)
+		ORDER BY updated_at, message_uuid
+		LIMIT $4`,
+		userID,
+		sinceTime,
+		sinceUUID,
+		limit+1,
+	)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "failed to query llmchat message tombstones")
+	}
+	entries, err := convertRowsToMessageTombstones(rows)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "")
+	}
+	return truncateMessageTombstones(entries, limit)
+}
+
+// Each Get*Diff/Get*Tombstones query above asks for one extra row beyond
+// limit; truncate* strips it back off and turns its presence into hasMore,
+// so the caller learns there's another page without a second round trip.
+
+func truncateSessionDiffEntries(entries []model.SessionDiffEntry, limit int16) ([]model.SessionDiffEntry, bool, error) {
+	if len(entries) > int(limit) {
+		return entries[:limit], true, nil
+	}
+	return entries, false, nil
+}
+
+func truncateMessageDiffEntries(entries []model.MessageDiffEntry, limit int16) ([]model.MessageDiffEntry, bool, error) {
+	if len(entries) > int(limit) {
+		return entries[:limit], true, nil
+	}
+	return entries, false, nil
+}
+
+func truncateSessionTombstones(entries []model.SessionTombstone, limit int16) ([]model.SessionTombstone, bool, error) {
+	if len(entries) > int(limit) {
+		return entries[:limit], true, nil
+	}
+	return entries, false, nil
+}
+
+func truncateMessageTombstones(entries []model.MessageTombstone, limit int16) ([]model.MessageTombstone, bool, error) {
+	if len(entries) > int(limit) {
+		return entries[:limit], true, nil
+	}
+	return entries, false, nil
+}
+
+func convertRowsToSessionDiffEntries(rows *sql.Rows) ([]model.SessionDiffEntry, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	entries := make([]model.SessionDiffEntry, 0)
+	for rows.Next() {
+		var entry model.SessionDiffEntry
+		if err := rows.Scan(
+			&entry.SessionUUID,
+			&entry.EncryptedData,
+			&entry.Header,
+			&entry.CreatedAt,
+			&entry.UpdatedAt,
+		); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan llmchat session diff")
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate llmchat session diff")
+	}
+	return entries, nil
+}
+
+func convertRowsToMessageDiffEntries(rows *sql.Rows) ([]model.MessageDiffEntry, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	entries := make([]model.MessageDiffEntry, 0)
+	for rows.Next() {
+		var entry model.MessageDiffEntry
+		var parentMessageUUID sql.NullString
+		if err := rows.Scan(
+			&entry.MessageUUID,
+			&entry.SessionUUID,
+			&parentMessageUUID,
+			&entry.EncryptedData,
+			&entry.Header,
+			&entry.CreatedAt,
+			&entry.UpdatedAt,
+		); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan llmchat message diff")
+		}
+		if parentMessageUUID.Valid {
+			entry.ParentMessageUUID = &parentMessageUUID.String
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate llmchat message diff")
+	}
+	return entries, nil
+}
+
+func convertRowsToSessionTombstones(rows *sql.Rows) ([]model.SessionTombstone, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	tombstones := make([]model.SessionTombstone, 0)
+	for rows.Next() {
+		var entry model.SessionTombstone
+		if err := rows.Scan(&entry.SessionUUID, &entry.DeletedAt); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan llmchat session tombstone")
+		}
+		tombstones = append(tombstones, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate llmchat session tombstones")
+	}
+	return tombstones, nil
+}
+
+func convertRowsToMessageTombstones(rows *sql.Rows) ([]model.MessageTombstone, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}()
+
+	tombstones := make([]model.MessageTombstone, 0)
+	for rows.Next() {
+		var entry model.MessageTombstone
+		if err := rows.Scan(&entry.MessageUUID, &entry.DeletedAt); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan llmchat message tombstone")
+		}
+		tombstones = append(tombstones, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate llmchat message tombstones")
+	}
+	return tombstones, nil
+}
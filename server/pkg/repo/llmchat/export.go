@@ -0,0 +1,100 @@
+package llmchat
+
+import (
+	"context"
+	"database/sql"
+
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/stacktrace"
+)
+
+// ListSessionsForExport returns up to limit non-deleted sessions updated
+// strictly after the (sinceTime, sinceUUID) keyset position, plus whether
+// more rows exist beyond this page. Pairing the timestamp with a tiebreaker
+// uuid (instead of paging on updated_at alone) means two sessions sharing an
+// updated_at microsecond under a batch upsert can't have one of them
+// silently skipped when the caller's cursor steps past that timestamp.
+func (r *Repository) ListSessionsForExport(ctx context.Context, userID int64, sinceTime int64, sinceUUID string, limit int) ([]model.Session, bool, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT session_uuid, user_id, encrypted_data, header, is_deleted, created_at, updated_at
+		FROM ensu_chat_sessions
+		WHERE user_id = $1 AND is_deleted = FALSE AND (updated_at, session_uuid) > ($2, $3)
+		ORDER BY updated_at, session_uuid
+		LIMIT $4`,
+		userID,
+		sinceTime,
+		sinceUUID,
+		limit+1,
+	)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "failed to query llmchat sessions for export")
+	}
+	defer rows.Close() // nolint: errcheck
+
+	sessions := make([]model.Session, 0, limit)
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(
+			&session.SessionUUID,
+			&session.UserID,
+			&session.EncryptedData,
+			&session.Header,
+			&session.IsDeleted,
+			&session.CreatedAt,
+			&session.UpdatedAt,
+		); err != nil {
+			return nil, false, stacktrace.Propagate(err, "failed to scan llmchat session for export")
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, stacktrace.Propagate(err, "failed to iterate llmchat sessions for export")
+	}
+	if len(sessions) > limit {
+		return sessions[:limit], true, nil
+	}
+	return sessions, false, nil
+}
+
+// ListMessagesForSessionExport returns every non-deleted message belonging
+// to sessionUUID, ordered so a client can rebuild the conversation tree by
+// walking ParentMessageUUID without re-deriving structure from timestamps.
+func (r *Repository) ListMessagesForSessionExport(ctx context.Context, userID int64, sessionUUID string) ([]model.Message, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT message_uuid, user_id, session_uuid, parent_message_uuid, encrypted_data, header, is_deleted, created_at, updated_at
+		FROM ensu_chat_messages
+		WHERE user_id = $1 AND session_uuid = $2 AND is_deleted = FALSE
+		ORDER BY created_at, message_uuid`,
+		userID,
+		sessionUUID,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to query llmchat messages for export")
+	}
+	defer rows.Close() // nolint: errcheck
+
+	messages := make([]model.Message, 0)
+	for rows.Next() {
+		var message model.Message
+		var parentMessageUUID sql.NullString
+		if err := rows.Scan(
+			&message.MessageUUID,
+			&message.UserID,
+			&message.SessionUUID,
+			&parentMessageUUID,
+			&message.EncryptedData,
+			&message.Header,
+			&message.IsDeleted,
+			&message.CreatedAt,
+			&message.UpdatedAt,
+		); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan llmchat message for export")
+		}
+		if parentMessageUUID.Valid {
+			message.ParentMessageUUID = &parentMessageUUID.String
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate llmchat messages for export")
+	}
+	return messages, nil
+}
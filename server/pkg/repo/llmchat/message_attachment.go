@@ -0,0 +1,110 @@
+package llmchat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ente-io/museum/ente"
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/stacktrace"
+)
+
+// AttachFile links fileID to messageUUID, storing the file's key wrapped
+// under a per-attachment secret -- never the llmchat session key -- so one
+// shared attachment can't be used to derive access to the rest of the
+// conversation. The insert is conditioned on messageUUID already belonging
+// to userID; the caller is responsible for having separately verified that
+// userID owns fileID itself.
+func (r *Repository) AttachFile(ctx context.Context, userID int64, messageUUID string, fileID int64, encryptedKey string, keyDecryptionNonce string) (model.Attachment, error) {
+	row := r.DB.QueryRowContext(ctx, `INSERT INTO ensu_chat_message_attachments(
+		message_uuid,
+		file_id,
+		user_id,
+		encrypted_key,
+		key_decryption_nonce
+	)
+	SELECT $1, $2, $3, $4, $5
+	WHERE EXISTS (SELECT 1 FROM ensu_chat_messages WHERE message_uuid = $1 AND user_id = $3)
+	ON CONFLICT (message_uuid, file_id) DO UPDATE
+		SET encrypted_key = EXCLUDED.encrypted_key,
+			key_decryption_nonce = EXCLUDED.key_decryption_nonce
+	RETURNING message_uuid, file_id, encrypted_key, key_decryption_nonce, created_at`,
+		messageUUID,
+		fileID,
+		userID,
+		encryptedKey,
+		keyDecryptionNonce,
+	)
+
+	var attachment model.Attachment
+	if err := row.Scan(
+		&attachment.MessageUUID,
+		&attachment.FileID,
+		&attachment.EncryptedKey,
+		&attachment.KeyDecryptionNonce,
+		&attachment.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return attachment, stacktrace.Propagate(&ente.ErrNotFoundError, "llmchat message not found")
+		}
+		return attachment, stacktrace.Propagate(err, "failed to attach file to llmchat message")
+	}
+	return attachment, nil
+}
+
+// DetachFile removes a single attachment, leaving every other file linked
+// to the message untouched.
+func (r *Repository) DetachFile(ctx context.Context, userID int64, messageUUID string, fileID int64) error {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM ensu_chat_message_attachments
+		WHERE message_uuid = $1 AND file_id = $2 AND user_id = $3`,
+		messageUUID,
+		fileID,
+		userID,
+	)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to detach file from llmchat message")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to confirm llmchat attachment detach")
+	}
+	if affected == 0 {
+		return stacktrace.Propagate(&ente.ErrNotFoundError, "llmchat attachment not found")
+	}
+	return nil
+}
+
+// ListAttachments returns every file linked to messageUUID, oldest first.
+func (r *Repository) ListAttachments(ctx context.Context, userID int64, messageUUID string) ([]model.Attachment, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT message_uuid, file_id, encrypted_key, key_decryption_nonce, created_at
+		FROM ensu_chat_message_attachments
+		WHERE message_uuid = $1 AND user_id = $2
+		ORDER BY created_at`,
+		messageUUID,
+		userID,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to query llmchat message attachments")
+	}
+	defer rows.Close() // nolint: errcheck
+
+	attachments := make([]model.Attachment, 0)
+	for rows.Next() {
+		var attachment model.Attachment
+		if err := rows.Scan(
+			&attachment.MessageUUID,
+			&attachment.FileID,
+			&attachment.EncryptedKey,
+			&attachment.KeyDecryptionNonce,
+			&attachment.CreatedAt,
+		); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan llmchat message attachment")
+		}
+		attachments = append(attachments, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate llmchat message attachments")
+	}
+	return attachments, nil
+}
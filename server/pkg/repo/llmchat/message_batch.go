@@ -0,0 +1,141 @@
+package llmchat
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ente-io/museum/ente"
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/stacktrace"
+	"github.com/lib/pq"
+)
+
+// UpsertMessagesBatch reconciles many messages in a single transaction
+// instead of the one-round-trip-per-message cost of UpsertMessage. It
+// returns a result and an error slice, both in request order and the same
+// length as reqs, so a caller can tell exactly which rows in its batch
+// landed and which didn't without re-deriving indices itself.
+func (r *Repository) UpsertMessagesBatch(ctx context.Context, userID int64, reqs []model.UpsertMessageRequest) ([]model.Message, []error) {
+	results := make([]model.Message, len(reqs))
+	errs := make([]error, len(reqs))
+	if len(reqs) == 0 {
+		return results, errs
+	}
+
+	fail := func(err error) ([]model.Message, []error) {
+		for i := range errs {
+			errs[i] = err
+		}
+		return results, errs
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fail(stacktrace.Propagate(err, "failed to begin llmchat message batch transaction"))
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	messageUUIDs := make([]string, len(reqs))
+	sessionUUIDs := make([]string, len(reqs))
+	parentMessageUUIDs := make([]sql.NullString, len(reqs))
+	encryptedData := make([]string, len(reqs))
+	headers := make([]string, len(reqs))
+	userIDs := make([]int64, len(reqs))
+	for i, req := range reqs {
+		messageUUIDs[i] = req.MessageUUID
+		sessionUUIDs[i] = req.SessionUUID
+		if req.ParentMessageUUID != nil {
+			parentMessageUUIDs[i] = sql.NullString{String: *req.ParentMessageUUID, Valid: true}
+		}
+		encryptedData[i] = req.EncryptedData
+		headers[i] = req.Header
+		userIDs[i] = userID
+	}
+
+	rows, err := tx.QueryContext(ctx, `INSERT INTO ensu_chat_messages(
+		message_uuid,
+		user_id,
+		session_uuid,
+		parent_message_uuid,
+		encrypted_data,
+		header,
+		is_deleted
+	)
+	SELECT message_uuid, user_id, session_uuid, parent_message_uuid, encrypted_data, header, FALSE
+	FROM unnest($1::uuid[], $2::bigint[], $3::uuid[], $4::uuid[], $5::text[], $6::text[])
+		AS t(message_uuid, user_id, session_uuid, parent_message_uuid, encrypted_data, header)
+	ON CONFLICT (message_uuid) DO UPDATE
+		SET session_uuid = EXCLUDED.session_uuid,
+			parent_message_uuid = EXCLUDED.parent_message_uuid,
+			encrypted_data = EXCLUDED.encrypted_data,
+			header = EXCLUDED.header,
+			is_deleted = FALSE
+		WHERE ensu_chat_messages.user_id = EXCLUDED.user_id
+	RETURNING message_uuid, user_id, session_uuid, parent_message_uuid, encrypted_data, header, is_deleted, created_at, updated_at`,
+		pq.Array(messageUUIDs),
+		pq.Array(userIDs),
+		pq.Array(sessionUUIDs),
+		pq.Array(parentMessageUUIDs),
+		pq.Array(encryptedData),
+		pq.Array(headers),
+	)
+	if err != nil {
+		return fail(stacktrace.Propagate(err, "failed to upsert llmchat message batch"))
+	}
+
+	byUUID := make(map[string]model.Message, len(reqs))
+	for rows.Next() {
+		var result model.Message
+		var parentMessageUUID sql.NullString
+		if scanErr := rows.Scan(
+			&result.MessageUUID,
+			&result.UserID,
+			&result.SessionUUID,
+			&parentMessageUUID,
+			&result.EncryptedData,
+			&result.Header,
+			&result.IsDeleted,
+			&result.CreatedAt,
+			&result.UpdatedAt,
+		); scanErr != nil {
+			_ = rows.Close()
+			return fail(stacktrace.Propagate(scanErr, "failed to scan llmchat message batch row"))
+		}
+		if parentMessageUUID.Valid {
+			result.ParentMessageUUID = &parentMessageUUID.String
+		}
+		byUUID[result.MessageUUID] = result
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return fail(stacktrace.Propagate(rowsErr, "failed to iterate llmchat message batch rows"))
+	}
+
+	// A request row missing from byUUID means the WHERE clause on ON
+	// CONFLICT DO UPDATE rejected it -- the only way that happens here is
+	// message_uuid already belonging to a different user.
+	for i, req := range reqs {
+		if msg, ok := byUUID[req.MessageUUID]; ok {
+			results[i] = msg
+		} else {
+			errs[i] = stacktrace.Propagate(ente.ErrPermissionDenied, "message_uuid belongs to another user")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		commitErr := stacktrace.Propagate(err, "failed to commit llmchat message batch transaction")
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = commitErr
+			}
+		}
+		return results, errs
+	}
+	committed = true
+
+	return results, errs
+}
@@ -0,0 +1,116 @@
+package llmchat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ente-io/museum/ente"
+	"github.com/ente-io/stacktrace"
+)
+
+// MultipartUpload is the bookkeeping row for an in-progress multipart
+// attachment upload: just enough to recover the upload ID and part size a
+// client needs to keep uploading parts or call Complete/Abort. It does not
+// itself track which parts have landed -- a client resuming after a
+// reconnect recovers that from storage.MultipartBackend's ListParts, not
+// from this row.
+type MultipartUpload struct {
+	AttachmentID string
+	UserID       int64
+	S3UploadID   string
+	PartSize     int64
+	CreatedAt    int64
+}
+
+// CreateMultipartUpload persists a new in-progress upload. attachmentID is
+// the conflict target: a client retrying CreateMultipartUpload for an
+// attachment it already started replaces the old upload state, since only
+// one multipart upload can be in progress for a given attachment at a time.
+func (r *Repository) CreateMultipartUpload(ctx context.Context, userID int64, attachmentID string, s3UploadID string, partSize int64) (MultipartUpload, error) {
+	row := r.DB.QueryRowContext(ctx, `INSERT INTO llm_chat_multipart_uploads(attachment_id, user_id, s3_upload_id, part_size)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (attachment_id) DO UPDATE
+			SET user_id = EXCLUDED.user_id,
+				s3_upload_id = EXCLUDED.s3_upload_id,
+				part_size = EXCLUDED.part_size,
+				created_at = now_utc_micro_seconds()
+		RETURNING attachment_id, user_id, s3_upload_id, part_size, created_at`,
+		attachmentID,
+		userID,
+		s3UploadID,
+		partSize,
+	)
+
+	var upload MultipartUpload
+	if err := row.Scan(&upload.AttachmentID, &upload.UserID, &upload.S3UploadID, &upload.PartSize, &upload.CreatedAt); err != nil {
+		return upload, stacktrace.Propagate(err, "failed to create llmchat multipart upload")
+	}
+	return upload, nil
+}
+
+// GetMultipartUpload fetches the in-progress upload for attachmentID, so a
+// client resuming after a reconnect can recover its uploadID and part size
+// without having to recreate the upload.
+func (r *Repository) GetMultipartUpload(ctx context.Context, userID int64, attachmentID string) (MultipartUpload, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT attachment_id, user_id, s3_upload_id, part_size, created_at
+		FROM llm_chat_multipart_uploads
+		WHERE attachment_id = $1 AND user_id = $2`,
+		attachmentID,
+		userID,
+	)
+
+	var upload MultipartUpload
+	if err := row.Scan(&upload.AttachmentID, &upload.UserID, &upload.S3UploadID, &upload.PartSize, &upload.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return upload, stacktrace.Propagate(&ente.ErrNotFoundError, "llmchat multipart upload not found")
+		}
+		return upload, stacktrace.Propagate(err, "failed to fetch llmchat multipart upload")
+	}
+	return upload, nil
+}
+
+// DeleteMultipartUpload removes the bookkeeping row for attachmentID, once
+// its upload has been completed or aborted.
+func (r *Repository) DeleteMultipartUpload(ctx context.Context, userID int64, attachmentID string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM llm_chat_multipart_uploads WHERE attachment_id = $1 AND user_id = $2`,
+		attachmentID,
+		userID,
+	)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to delete llmchat multipart upload")
+	}
+	return nil
+}
+
+// ListStaleMultipartUploads returns every multipart upload created before
+// olderThan, so a scheduled job can abort uploads a client never finished
+// or came back to.
+func (r *Repository) ListStaleMultipartUploads(ctx context.Context, olderThan time.Time, limit int) ([]MultipartUpload, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT attachment_id, user_id, s3_upload_id, part_size, created_at
+		FROM llm_chat_multipart_uploads
+		WHERE created_at < $1
+		ORDER BY created_at
+		LIMIT $2`,
+		olderThan.UnixMicro(),
+		limit,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to query stale llmchat multipart uploads")
+	}
+	defer rows.Close() // nolint: errcheck
+
+	uploads := make([]MultipartUpload, 0)
+	for rows.Next() {
+		var upload MultipartUpload
+		if err := rows.Scan(&upload.AttachmentID, &upload.UserID, &upload.S3UploadID, &upload.PartSize, &upload.CreatedAt); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan stale llmchat multipart upload")
+		}
+		uploads = append(uploads, upload)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate stale llmchat multipart uploads")
+	}
+	return uploads, nil
+}
@@ -0,0 +1,112 @@
+package llmchat
+
+import (
+	"context"
+
+	"github.com/ente-io/stacktrace"
+	"github.com/lib/pq"
+)
+
+// SweepableAttachment is a session_attachments row whose session has since
+// been tombstoned, so the object it points at is safe to delete.
+type SweepableAttachment struct {
+	ID           int64
+	UserID       int64
+	AttachmentID string
+}
+
+// RecordSessionAttachment notes that attachmentID was uploaded for
+// sessionUUID, so AttachmentGC can find and delete its object once the
+// session is tombstoned. The conflict target tolerates a client retrying
+// the same upload (or re-requesting a presigned URL for it) without
+// creating a duplicate row.
+func (r *Repository) RecordSessionAttachment(ctx context.Context, userID int64, sessionUUID string, attachmentID string) error {
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO session_attachments(session_uuid, attachment_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (session_uuid, attachment_id) DO NOTHING`,
+		sessionUUID,
+		attachmentID,
+		userID,
+	)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to record llmchat session attachment")
+	}
+	return nil
+}
+
+// ListSweepableAttachments returns up to limit attachments belonging to a
+// tombstoned session that haven't already been swept.
+func (r *Repository) ListSweepableAttachments(ctx context.Context, limit int) ([]SweepableAttachment, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT sa.id, sa.user_id, sa.attachment_id
+		FROM session_attachments sa
+		JOIN ensu_chat_sessions s ON s.session_uuid = sa.session_uuid
+		WHERE s.is_deleted = TRUE AND sa.swept = FALSE
+		ORDER BY sa.id
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to query sweepable llmchat attachments")
+	}
+	defer rows.Close() // nolint: errcheck
+
+	attachments := make([]SweepableAttachment, 0)
+	for rows.Next() {
+		var a SweepableAttachment
+		if err := rows.Scan(&a.ID, &a.UserID, &a.AttachmentID); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan sweepable llmchat attachment")
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate sweepable llmchat attachments")
+	}
+	return attachments, nil
+}
+
+// MarkAttachmentsSwept flags every row in ids as swept, so a later sweep
+// doesn't try to delete the same already-removed objects again.
+func (r *Repository) MarkAttachmentsSwept(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.DB.ExecContext(ctx, `UPDATE session_attachments SET swept = TRUE WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to mark llmchat attachments swept")
+	}
+	return nil
+}
+
+// ListAttachmentsForUser returns every attachment ID ever recorded for
+// userID, swept or not, so account deletion can purge all of them
+// regardless of whether their owning session was ever explicitly deleted.
+func (r *Repository) ListAttachmentsForUser(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT DISTINCT attachment_id FROM session_attachments WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to query llmchat attachments for user")
+	}
+	defer rows.Close() // nolint: errcheck
+
+	attachmentIDs := make([]string, 0)
+	for rows.Next() {
+		var attachmentID string
+		if err := rows.Scan(&attachmentID); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan llmchat attachment id")
+		}
+		attachmentIDs = append(attachmentIDs, attachmentID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate llmchat attachments for user")
+	}
+	return attachmentIDs, nil
+}
+
+// DeleteAttachmentRowsForUser removes every session_attachments row for
+// userID, once their objects have been purged from the store.
+func (r *Repository) DeleteAttachmentRowsForUser(ctx context.Context, userID int64) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM session_attachments WHERE user_id = $1`, userID)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to delete llmchat session attachments for user")
+	}
+	return nil
+}
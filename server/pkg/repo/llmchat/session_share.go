@@ -0,0 +1,227 @@
+package llmchat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ente-io/museum/ente"
+	model "github.com/ente-io/museum/ente/llmchat"
+	"github.com/ente-io/stacktrace"
+	"github.com/google/uuid"
+)
+
+// GetSessionByUUID fetches a single non-deleted session, scoped to userID so
+// a caller can't be handed (or act on) a session they don't own.
+func (r *Repository) GetSessionByUUID(ctx context.Context, userID int64, sessionUUID string) (model.Session, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT session_uuid, user_id, encrypted_data, header, is_deleted, created_at, updated_at
+		FROM ensu_chat_sessions
+		WHERE session_uuid = $1 AND user_id = $2 AND is_deleted = FALSE`,
+		sessionUUID,
+		userID,
+	)
+	var session model.Session
+	if err := row.Scan(
+		&session.SessionUUID,
+		&session.UserID,
+		&session.EncryptedData,
+		&session.Header,
+		&session.IsDeleted,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return session, stacktrace.Propagate(&ente.ErrNotFoundError, "llmchat session not found")
+		}
+		return session, stacktrace.Propagate(err, "failed to fetch llmchat session")
+	}
+	return session, nil
+}
+
+// GetSessionForShare is GetSessionByUUID's counterpart for resolving a
+// share: ownerID comes from the share row itself rather than the caller's
+// own auth, since ResolveSessionShare is deliberately unauthenticated.
+func (r *Repository) GetSessionForShare(ctx context.Context, ownerID int64, sessionUUID string) (model.Session, error) {
+	session, err := r.GetSessionByUUID(ctx, ownerID, sessionUUID)
+	if err != nil {
+		return session, stacktrace.Propagate(err, "")
+	}
+	return session, nil
+}
+
+// CreateSessionShare mints a new share for req.SessionUUID, wrapping
+// sessionKey under a share-specific secret exactly like a collection's
+// PublicURL wraps its own key per grantee.
+func (r *Repository) CreateSessionShare(ctx context.Context, userID int64, req model.CreateSessionShareRequest) (model.SessionShare, error) {
+	shareToken := uuid.New().String()
+	row := r.DB.QueryRowContext(ctx, `INSERT INTO session_shares(
+		share_token,
+		session_uuid,
+		owner_id,
+		encrypted_key,
+		key_decryption_nonce,
+		password_enabled,
+		nonce,
+		ops_limit,
+		mem_limit,
+		verifier_hash,
+		valid_till,
+		device_limit,
+		read_only
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING share_token, session_uuid, owner_id, encrypted_key, key_decryption_nonce, password_enabled, nonce, ops_limit, mem_limit, verifier_hash, valid_till, device_limit, read_only, created_at, updated_at`,
+		shareToken,
+		req.SessionUUID,
+		userID,
+		req.EncryptedKey,
+		req.KeyDecryptionNonce,
+		req.PasswordEnabled,
+		req.Nonce,
+		req.OpsLimit,
+		req.MemLimit,
+		req.VerifierHash,
+		req.ValidTill,
+		req.DeviceLimit,
+		req.ReadOnly,
+	)
+
+	var share model.SessionShare
+	if err := scanSessionShare(row, &share); err != nil {
+		return share, stacktrace.Propagate(err, "failed to create llmchat session share")
+	}
+	return share, nil
+}
+
+// UpdateSessionShare changes an existing share's expiry, device limit,
+// read-only flag, or password, scoped to userID so only the owning session's
+// owner can modify it. The share's token and wrapped key are left
+// untouched -- existing recipients keep the same link.
+func (r *Repository) UpdateSessionShare(ctx context.Context, userID int64, req model.UpdateSessionShareRequest) (model.SessionShare, error) {
+	row := r.DB.QueryRowContext(ctx, `UPDATE session_shares
+		SET password_enabled = $1,
+			nonce = $2,
+			ops_limit = $3,
+			mem_limit = $4,
+			verifier_hash = $5,
+			valid_till = $6,
+			device_limit = $7,
+			read_only = $8,
+			updated_at = now_utc_micro_seconds()
+		WHERE share_token = $9 AND owner_id = $10
+		RETURNING share_token, session_uuid, owner_id, encrypted_key, key_decryption_nonce, password_enabled, nonce, ops_limit, mem_limit, verifier_hash, valid_till, device_limit, read_only, created_at, updated_at`,
+		req.PasswordEnabled,
+		req.Nonce,
+		req.OpsLimit,
+		req.MemLimit,
+		req.VerifierHash,
+		req.ValidTill,
+		req.DeviceLimit,
+		req.ReadOnly,
+		req.ShareToken,
+		userID,
+	)
+
+	var share model.SessionShare
+	if err := scanSessionShare(row, &share); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return share, stacktrace.Propagate(&ente.ErrNotFoundError, "llmchat session share not found")
+		}
+		return share, stacktrace.Propagate(err, "failed to update llmchat session share")
+	}
+	return share, nil
+}
+
+// RevokeSessionShare deletes shareToken, scoped to userID so only the
+// owning session's owner can revoke it.
+func (r *Repository) RevokeSessionShare(ctx context.Context, userID int64, shareToken string) error {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM session_shares WHERE share_token = $1 AND owner_id = $2`,
+		shareToken,
+		userID,
+	)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to revoke llmchat session share")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to confirm llmchat session share revocation")
+	}
+	if affected == 0 {
+		return stacktrace.Propagate(&ente.ErrNotFoundError, "llmchat session share not found")
+	}
+	return nil
+}
+
+// GetSessionShareByToken fetches a share unauthenticated -- the share
+// itself (its password proof, or lack of one) is ResolveSessionShare's only
+// gate.
+func (r *Repository) GetSessionShareByToken(ctx context.Context, shareToken string) (model.SessionShare, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT share_token, session_uuid, owner_id, encrypted_key, key_decryption_nonce, password_enabled, nonce, ops_limit, mem_limit, verifier_hash, valid_till, device_limit, read_only, created_at, updated_at
+		FROM session_shares
+		WHERE share_token = $1`,
+		shareToken,
+	)
+	var share model.SessionShare
+	if err := scanSessionShare(row, &share); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return share, stacktrace.Propagate(&ente.ErrNotFoundError, "llmchat session share not found")
+		}
+		return share, stacktrace.Propagate(err, "failed to fetch llmchat session share")
+	}
+	return share, nil
+}
+
+// RecordSessionShareDevice registers deviceID against shareToken, enforcing
+// deviceLimit exactly like a collection PublicURL's device limit: a device
+// that's already resolved this share before is always let back in, and a
+// deviceLimit of zero or less means unlimited. It reports whether deviceID
+// is (now, or already was) within the limit.
+func (r *Repository) RecordSessionShareDevice(ctx context.Context, shareToken string, deviceID string, deviceLimit int64) (bool, error) {
+	var alreadyKnown bool
+	row := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM session_share_devices WHERE share_token = $1 AND device_id = $2)`,
+		shareToken,
+		deviceID,
+	)
+	if err := row.Scan(&alreadyKnown); err != nil {
+		return false, stacktrace.Propagate(err, "failed to check llmchat session share device")
+	}
+	if alreadyKnown {
+		return true, nil
+	}
+
+	result, err := r.DB.ExecContext(ctx, `INSERT INTO session_share_devices(share_token, device_id)
+		SELECT $1, $2
+		WHERE $3 <= 0 OR (SELECT COUNT(*) FROM session_share_devices WHERE share_token = $1) < $3
+		ON CONFLICT (share_token, device_id) DO NOTHING`,
+		shareToken,
+		deviceID,
+		deviceLimit,
+	)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "failed to record llmchat session share device")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, stacktrace.Propagate(err, "failed to confirm llmchat session share device")
+	}
+	return affected > 0, nil
+}
+
+func scanSessionShare(row *sql.Row, share *model.SessionShare) error {
+	return row.Scan(
+		&share.ShareToken,
+		&share.SessionUUID,
+		&share.OwnerID,
+		&share.EncryptedKey,
+		&share.KeyDecryptionNonce,
+		&share.PasswordEnabled,
+		&share.Nonce,
+		&share.OpsLimit,
+		&share.MemLimit,
+		&share.VerifierHash,
+		&share.ValidTill,
+		&share.DeviceLimit,
+		&share.ReadOnly,
+		&share.CreatedAt,
+		&share.UpdatedAt,
+	)
+}
@@ -0,0 +1,196 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ente-io/museum/ente"
+	"github.com/ente-io/stacktrace"
+)
+
+// GranteeAuthMethod identifies how a grant's wrapped collection key is
+// protected: by a password-derived secret, or by an ECDH shared secret
+// derived from the grantee's own key pair.
+type GranteeAuthMethod string
+
+const (
+	GranteeAuthPassword GranteeAuthMethod = "password"
+	GranteeAuthKeyPair  GranteeAuthMethod = "key_pair"
+)
+
+// PublicLinkGrant is one entry in a collection's public-link ACL: the
+// collection key wrapped for a single grantee, the material needed to
+// re-derive the wrapping secret, and a verifier the server can check the
+// grantee's proof against without ever seeing the wrapping secret itself.
+type PublicLinkGrant struct {
+	CollectionID       int64
+	GranteeID          string
+	AuthMethod         GranteeAuthMethod
+	EncryptedKey       string
+	KeyDecryptionNonce string
+	VerifierHash       string
+	Nonce              string
+	OpsLimit           int64
+	MemLimit           int64
+	PublicKey          *string
+	CreatedAt          int64
+	UpdatedAt          int64
+}
+
+// PublicLinkGrantRepository stores the ACL for collection public links,
+// letting an owner grant or revoke individual recipients without touching
+// the link's single PublicURL row or affecting any other grantee.
+type PublicLinkGrantRepository struct {
+	DB *sql.DB
+}
+
+// AddGrant creates or replaces the grant for (collectionID, granteeID).
+func (r *PublicLinkGrantRepository) AddGrant(ctx context.Context, collectionID int64, granteeID string, authMethod GranteeAuthMethod, encryptedKey string, keyDecryptionNonce string, verifierHash string, nonce string, opsLimit int64, memLimit int64, publicKey *string) (PublicLinkGrant, error) {
+	row := r.DB.QueryRowContext(ctx, `INSERT INTO public_collection_grants(
+		collection_id,
+		grantee_id,
+		auth_method,
+		encrypted_key,
+		key_decryption_nonce,
+		verifier_hash,
+		nonce,
+		ops_limit,
+		mem_limit,
+		public_key
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (collection_id, grantee_id) DO UPDATE
+		SET auth_method = EXCLUDED.auth_method,
+			encrypted_key = EXCLUDED.encrypted_key,
+			key_decryption_nonce = EXCLUDED.key_decryption_nonce,
+			verifier_hash = EXCLUDED.verifier_hash,
+			nonce = EXCLUDED.nonce,
+			ops_limit = EXCLUDED.ops_limit,
+			mem_limit = EXCLUDED.mem_limit,
+			public_key = EXCLUDED.public_key,
+			updated_at = now_utc_micro_seconds()
+	RETURNING collection_id, grantee_id, auth_method, encrypted_key, key_decryption_nonce, verifier_hash, nonce, ops_limit, mem_limit, public_key, created_at, updated_at`,
+		collectionID,
+		granteeID,
+		authMethod,
+		encryptedKey,
+		keyDecryptionNonce,
+		verifierHash,
+		nonce,
+		opsLimit,
+		memLimit,
+		publicKey,
+	)
+
+	var grant PublicLinkGrant
+	if err := row.Scan(
+		&grant.CollectionID,
+		&grant.GranteeID,
+		&grant.AuthMethod,
+		&grant.EncryptedKey,
+		&grant.KeyDecryptionNonce,
+		&grant.VerifierHash,
+		&grant.Nonce,
+		&grant.OpsLimit,
+		&grant.MemLimit,
+		&grant.PublicKey,
+		&grant.CreatedAt,
+		&grant.UpdatedAt,
+	); err != nil {
+		return grant, stacktrace.Propagate(err, "failed to upsert public link grant")
+	}
+	return grant, nil
+}
+
+// ListGrants returns every grant on collectionID, oldest first.
+func (r *PublicLinkGrantRepository) ListGrants(ctx context.Context, collectionID int64) ([]PublicLinkGrant, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT collection_id, grantee_id, auth_method, encrypted_key, key_decryption_nonce, verifier_hash, nonce, ops_limit, mem_limit, public_key, created_at, updated_at
+		FROM public_collection_grants
+		WHERE collection_id = $1
+		ORDER BY created_at`,
+		collectionID,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to query public link grants")
+	}
+	defer rows.Close() // nolint: errcheck
+
+	grants := make([]PublicLinkGrant, 0)
+	for rows.Next() {
+		var grant PublicLinkGrant
+		if err := rows.Scan(
+			&grant.CollectionID,
+			&grant.GranteeID,
+			&grant.AuthMethod,
+			&grant.EncryptedKey,
+			&grant.KeyDecryptionNonce,
+			&grant.VerifierHash,
+			&grant.Nonce,
+			&grant.OpsLimit,
+			&grant.MemLimit,
+			&grant.PublicKey,
+			&grant.CreatedAt,
+			&grant.UpdatedAt,
+		); err != nil {
+			return nil, stacktrace.Propagate(err, "failed to scan public link grant")
+		}
+		grants = append(grants, grant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "failed to iterate public link grants")
+	}
+	return grants, nil
+}
+
+// GetGrant fetches a single grant, used when resolving a link for a
+// specific grantee.
+func (r *PublicLinkGrantRepository) GetGrant(ctx context.Context, collectionID int64, granteeID string) (PublicLinkGrant, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT collection_id, grantee_id, auth_method, encrypted_key, key_decryption_nonce, verifier_hash, nonce, ops_limit, mem_limit, public_key, created_at, updated_at
+		FROM public_collection_grants
+		WHERE collection_id = $1 AND grantee_id = $2`,
+		collectionID,
+		granteeID,
+	)
+
+	var grant PublicLinkGrant
+	if err := row.Scan(
+		&grant.CollectionID,
+		&grant.GranteeID,
+		&grant.AuthMethod,
+		&grant.EncryptedKey,
+		&grant.KeyDecryptionNonce,
+		&grant.VerifierHash,
+		&grant.Nonce,
+		&grant.OpsLimit,
+		&grant.MemLimit,
+		&grant.PublicKey,
+		&grant.CreatedAt,
+		&grant.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return grant, stacktrace.Propagate(&ente.ErrNotFoundError, "grant not found")
+		}
+		return grant, stacktrace.Propagate(err, "failed to fetch public link grant")
+	}
+	return grant, nil
+}
+
+// RevokeGrant removes a single grantee's access, leaving every other grant
+// on the collection untouched.
+func (r *PublicLinkGrantRepository) RevokeGrant(ctx context.Context, collectionID int64, granteeID string) error {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM public_collection_grants WHERE collection_id = $1 AND grantee_id = $2`,
+		collectionID,
+		granteeID,
+	)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to revoke public link grant")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to confirm public link grant revocation")
+	}
+	if affected == 0 {
+		return stacktrace.Propagate(&ente.ErrNotFoundError, "grant not found")
+	}
+	return nil
+}
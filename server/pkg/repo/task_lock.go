@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ente-io/stacktrace"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultLeaseRefreshFraction controls how often a Lease is refreshed,
+// expressed as a fraction of its TTL, so a missed refresh or two still
+// leaves headroom before the row actually expires.
+const defaultLeaseRefreshFraction = 3
+
+// ErrLeaseHeld is returned by AcquireLease when lockKey is already held by an
+// unexpired lease. Metadata carries whatever the current holder stored,
+// letting callers like BackupWithScope surface the in-flight job's ID.
+type ErrLeaseHeld struct {
+	Metadata string
+}
+
+func (e *ErrLeaseHeld) Error() string {
+	return "lease is already held"
+}
+
+// TaskLockRepository persists long-running-job locks so that only one
+// worker (in-process or across replicas) makes progress on a given key at a
+// time.
+type TaskLockRepository struct {
+	DB *sql.DB
+}
+
+// Lease is a DB-backed lock with a context that's canceled the moment the
+// lease is lost, either because Release was called or because the
+// background refresher failed to extend it in time. Callers doing
+// long-running enumeration/upload work should select on Context.Done()
+// and abort promptly.
+type Lease struct {
+	Key     string
+	Context context.Context
+	cancel  context.CancelFunc
+	repo    *TaskLockRepository
+	token   string
+	stop    chan struct{}
+}
+
+// AcquireLease tries to take ownership of lockKey for ttl, storing metadata
+// (e.g. a job ID) alongside it so a rejected caller can report what's
+// already running. It starts a background goroutine that refreshes the
+// lease's expiry until Release is called; if a refresh fails, the Lease's
+// Context is canceled so in-flight work can abort cleanly instead of
+// continuing to run past the point another worker might pick up the key.
+func (r *TaskLockRepository) AcquireLease(ctx context.Context, lockKey string, ttl time.Duration, metadata string) (*Lease, error) {
+	token := uuid.New().String()
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	row := r.DB.QueryRowContext(ctx, `INSERT INTO task_leases(lock_key, owner_token, metadata, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (lock_key) DO UPDATE
+			SET owner_token = EXCLUDED.owner_token,
+				metadata = EXCLUDED.metadata,
+				expires_at = EXCLUDED.expires_at
+			WHERE task_leases.expires_at < $5
+		RETURNING owner_token`,
+		lockKey, token, metadata, expiresAt, now)
+
+	var returnedToken string
+	switch err := row.Scan(&returnedToken); {
+	case errors.Is(err, sql.ErrNoRows):
+		existingMetadata, fetchErr := r.metadataFor(ctx, lockKey)
+		if fetchErr != nil {
+			return nil, stacktrace.Propagate(fetchErr, "failed to fetch existing lease metadata")
+		}
+		return nil, &ErrLeaseHeld{Metadata: existingMetadata}
+	case err != nil:
+		return nil, stacktrace.Propagate(err, "failed to acquire lease")
+	}
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	lease := &Lease{
+		Key:     lockKey,
+		Context: leaseCtx,
+		cancel:  cancel,
+		repo:    r,
+		token:   token,
+		stop:    make(chan struct{}),
+	}
+	go lease.refreshUntilReleased(ttl)
+	return lease, nil
+}
+
+func (r *TaskLockRepository) metadataFor(ctx context.Context, lockKey string) (string, error) {
+	var metadata string
+	row := r.DB.QueryRowContext(ctx, `SELECT metadata FROM task_leases WHERE lock_key = $1`, lockKey)
+	if err := row.Scan(&metadata); err != nil {
+		return "", err
+	}
+	return metadata, nil
+}
+
+// PeekLeaseMetadata returns whatever metadata is currently stored against
+// lockKey, without taking the lease -- a resumable job calls this before
+// AcquireLease to recover the progress checkpoint left by a prior run that
+// crashed or was preempted, rather than starting over from scratch. An
+// empty string (with a nil error) means no row exists yet.
+func (r *TaskLockRepository) PeekLeaseMetadata(ctx context.Context, lockKey string) (string, error) {
+	metadata, err := r.metadataFor(ctx, lockKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to fetch lease metadata")
+	}
+	return metadata, nil
+}
+
+// refreshUntilReleased extends the lease's expiry every ttl/N until Release
+// stops it. If a refresh affects zero rows (the row was deleted or raced
+// with another acquirer) or the DB call itself errors, the lease is
+// considered lost and its Context is canceled.
+func (l *Lease) refreshUntilReleased(ttl time.Duration) {
+	interval := ttl / defaultLeaseRefreshFraction
+	if interval <= 0 {
+		interval = ttl
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if !l.extend(ttl) {
+				l.cancel()
+				return
+			}
+		}
+	}
+}
+
+// extend pushes the lease's expiry out by ttl from now, but only while this
+// Lease still owns the row. It reports whether the lease is still held.
+func (l *Lease) extend(ttl time.Duration) bool {
+	result, err := l.repo.DB.Exec(`UPDATE task_leases
+		SET expires_at = $1
+		WHERE lock_key = $2 AND owner_token = $3`,
+		time.Now().Add(ttl), l.Key, l.token)
+	if err != nil {
+		log.WithError(err).WithField("lock_key", l.Key).Warning("failed to refresh task lease")
+		return false
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		log.WithField("lock_key", l.Key).Warning("task lease was lost")
+		return false
+	}
+	return true
+}
+
+// Refresh updates the lease's stored metadata (e.g. a progress checkpoint)
+// without changing its expiry, so a long-running job can persist how far
+// it's gotten without waiting on the background refresher's own timer.
+// Reports whether the lease is still held.
+func (l *Lease) Refresh(metadata string) bool {
+	result, err := l.repo.DB.Exec(`UPDATE task_leases
+		SET metadata = $1
+		WHERE lock_key = $2 AND owner_token = $3`,
+		metadata, l.Key, l.token)
+	if err != nil {
+		log.WithError(err).WithField("lock_key", l.Key).Warning("failed to persist task lease progress")
+		return false
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		log.WithField("lock_key", l.Key).Warning("task lease was lost")
+		return false
+	}
+	return true
+}
+
+// Release stops the background refresher and deletes the lease row if this
+// Lease still owns it. Safe to call even if the lease was already lost.
+//
+// Only call Release when the job actually finished (or should no longer be
+// resumed) -- it deletes whatever checkpoint metadata Refresh has persisted.
+// A job that wants to abort but be resumable from its last checkpoint should
+// call Abandon instead.
+func (l *Lease) Release() {
+	close(l.stop)
+	l.cancel()
+	if _, err := l.repo.DB.Exec(`DELETE FROM task_leases WHERE lock_key = $1 AND owner_token = $2`, l.Key, l.token); err != nil {
+		log.WithError(err).WithField("lock_key", l.Key).Warning("failed to release task lease")
+	}
+}
+
+// Abandon stops the background refresher without deleting the lease row, so
+// whatever checkpoint metadata the job last persisted via Refresh survives
+// for a future run to resume from. Use this on an error/abort path where
+// Release would otherwise wipe the very progress the job just recorded; the
+// row is left to expire on its own TTL so a stuck job doesn't block retries
+// forever.
+func (l *Lease) Abandon() {
+	close(l.stop)
+	l.cancel()
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/ente-io/stacktrace"
+)
+
+// AzureBackend stores objects as blobs in a single Azure Blob Storage
+// container.
+type AzureBackend struct {
+	containerURL azblob.ContainerURL
+	credential   azblob.StorageAccountCredential
+}
+
+// NewAzureBackend builds a Backend backed by an Azure Blob Storage container.
+func NewAzureBackend(accountName string, accountKey string, containerName string) (*AzureBackend, error) {
+	credential, err := azblob.NewSharedKeyCredential(accountName, accountKey)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create azure credential")
+	}
+	containerURL, err := url.Parse(
+		"https://" + accountName + ".blob.core.windows.net/" + containerName,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to parse azure container url")
+	}
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	return &AzureBackend{
+		containerURL: azblob.NewContainerURL(*containerURL, pipeline),
+		credential:   credential,
+	}, nil
+}
+
+func (b *AzureBackend) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	blobURL := b.containerURL.NewBlockBlobURL(key)
+	_, err := azblob.UploadStreamToBlockBlob(ctx, body, blobURL, azblob.UploadStreamToBlockBlobOptions{
+		BufferSize: 4 * 1024 * 1024,
+		MaxBuffers: 4,
+		BlobHTTPHeaders: azblob.BlobHTTPHeaders{
+			ContentType: contentType,
+		},
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to put blob")
+	}
+	return nil
+}
+
+func (b *AzureBackend) Get(ctx context.Context, key string) (io.ReadCloser, ObjectMeta, error) {
+	blobURL := b.containerURL.NewBlockBlobURL(key)
+	resp, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		if isAzureNotFound(err) {
+			return nil, ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		return nil, ObjectMeta{}, stacktrace.Propagate(err, "failed to download blob")
+	}
+	meta := ObjectMeta{
+		Size:        resp.ContentLength(),
+		ContentType: resp.ContentType(),
+	}
+	return resp.Body(azblob.RetryReaderOptions{}), meta, nil
+}
+
+func (b *AzureBackend) Head(ctx context.Context, key string) (ObjectMeta, error) {
+	blobURL := b.containerURL.NewBlockBlobURL(key)
+	props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		if isAzureNotFound(err) {
+			return ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		return ObjectMeta{}, stacktrace.Propagate(err, "failed to fetch blob properties")
+	}
+	return ObjectMeta{Size: props.ContentLength(), ContentType: props.ContentType()}, nil
+}
+
+func (b *AzureBackend) Delete(ctx context.Context, key string) error {
+	blobURL := b.containerURL.NewBlockBlobURL(key)
+	_, err := blobURL.Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+	if err != nil && !isAzureNotFound(err) {
+		return stacktrace.Propagate(err, "failed to delete blob")
+	}
+	return nil
+}
+
+func (b *AzureBackend) PresignPut(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return b.sasURL(key, ttl, azblob.BlobSASPermissions{Write: true, Create: true})
+}
+
+func (b *AzureBackend) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return b.sasURL(key, ttl, azblob.BlobSASPermissions{Read: true})
+}
+
+func (b *AzureBackend) sasURL(key string, ttl time.Duration, perms azblob.BlobSASPermissions) (string, error) {
+	blobURL := b.containerURL.NewBlockBlobURL(key)
+	sharedKeyCredential, ok := b.credential.(*azblob.SharedKeyCredential)
+	if !ok {
+		return "", stacktrace.Propagate(ErrPresignUnsupported, "azure backend was not configured with a shared key credential")
+	}
+	sasQueryParams, err := azblob.BlobSASSignatureValues{
+		Protocol:      azblob.SASProtocolHTTPS,
+		ExpiryTime:    time.Now().UTC().Add(ttl),
+		ContainerName: b.containerURL.String(),
+		BlobName:      key,
+		Permissions:   perms.String(),
+	}.NewSASQueryParameters(sharedKeyCredential)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to sign sas url")
+	}
+	qp := sasQueryParams.Encode()
+	return blobURL.URL().String() + "?" + qp, nil
+}
+
+func isAzureNotFound(err error) bool {
+	storageErr, ok := err.(azblob.StorageError)
+	return ok && storageErr.ServiceCode() == azblob.ServiceCodeBlobNotFound
+}
@@ -0,0 +1,62 @@
+// Package storage provides a pluggable object storage abstraction so that
+// museum isn't hard-wired to a single S3-compatible endpoint. Collections can
+// be tiered across backends (e.g. "hot" S3, "cold" Glacier-backed storage, a
+// self-hosted MinIO, or a region-pinned Azure/GCS bucket) by storage class.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrObjectNotFound is returned by Get and Head when key doesn't exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ErrPresignUnsupported is returned by PresignPut/PresignGet when a backend
+// was configured with credentials that can't sign URLs (e.g. an Azure
+// backend whose credential is not a shared key credential).
+var ErrPresignUnsupported = errors.New("backend cannot presign urls with its configured credentials")
+
+// ObjectMeta describes an object's size and content type, as returned by
+// Head and Get.
+type ObjectMeta struct {
+	Size        int64
+	ContentType string
+}
+
+// BatchDeleter is implemented by backends that can delete many objects in a
+// single round trip (e.g. S3's DeleteObjects). A caller sweeping a large
+// batch of keys should type-assert for it and fall back to deleting one key
+// at a time against a Backend that doesn't.
+type BatchDeleter interface {
+	// DeleteBatch removes every object in keys. It is not an error for a
+	// key to not exist. err is non-nil only when the request itself failed
+	// (e.g. a transport error); per-key failures inside an otherwise
+	// successful request are reported as failedKeys instead, since a
+	// backend like S3 can return 200 OK with some keys rejected. A caller
+	// must not assume every key was deleted just because err is nil.
+	// Implementations may cap how many keys a single call accepts; callers
+	// sweeping more than that must chunk themselves.
+	DeleteBatch(ctx context.Context, keys []string) (failedKeys []string, err error)
+}
+
+// Backend is implemented by every concrete object storage integration
+// (S3, MinIO, Azure Blob, GCS, ...). All methods are safe for concurrent use.
+type Backend interface {
+	// Put uploads size bytes read from body to key, overwriting any existing
+	// object at that key.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns a reader for the object at key. Callers must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, ObjectMeta, error)
+	// Head returns an object's metadata without downloading its body.
+	Head(ctx context.Context, key string) (ObjectMeta, error)
+	// Delete removes the object at key. It is not an error for key to not
+	// exist.
+	Delete(ctx context.Context, key string) error
+	// PresignPut returns a URL the caller can PUT to directly, valid for ttl.
+	PresignPut(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// PresignGet returns a URL the caller can GET directly, valid for ttl.
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
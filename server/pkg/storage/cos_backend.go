@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ente-io/stacktrace"
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// CosBackend stores objects in a single Tencent Cloud Object Storage (COS)
+// bucket. COS speaks a mostly S3-compatible API, but ships its own Go SDK
+// with request signing baked in, so it gets its own Backend rather than
+// reusing S3Backend's client.
+type CosBackend struct {
+	client *cos.Client
+}
+
+// NewCosBackend builds a Backend backed by a Tencent COS bucket. bucketURL
+// is the bucket's full endpoint, e.g.
+// "https://examplebucket-1250000000.cos.ap-guangzhou.myqcloud.com".
+func NewCosBackend(bucketURL string, secretID string, secretKey string) (*CosBackend, error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to parse cos bucket url")
+	}
+	client := cos.NewClient(&cos.BaseURL{BucketURL: u}, &http.Client{
+		Transport: &cos.AuthorizationTransport{
+			SecretID:  secretID,
+			SecretKey: secretKey,
+		},
+	})
+	return &CosBackend{client: client}, nil
+}
+
+func (b *CosBackend) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	_, err := b.client.Object.Put(ctx, key, body, &cos.ObjectPutOptions{
+		ObjectPutHeaderOptions: &cos.ObjectPutHeaderOptions{
+			ContentLength: size,
+			ContentType:   contentType,
+		},
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to put cos object")
+	}
+	return nil
+}
+
+func (b *CosBackend) Get(ctx context.Context, key string) (io.ReadCloser, ObjectMeta, error) {
+	resp, err := b.client.Object.Get(ctx, key, nil)
+	if err != nil {
+		if isCosNotFound(err) {
+			return nil, ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		return nil, ObjectMeta{}, stacktrace.Propagate(err, "failed to get cos object")
+	}
+	return resp.Body, ObjectMeta{Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}, nil
+}
+
+func (b *CosBackend) Head(ctx context.Context, key string) (ObjectMeta, error) {
+	resp, err := b.client.Object.Head(ctx, key, nil)
+	if err != nil {
+		if isCosNotFound(err) {
+			return ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		return ObjectMeta{}, stacktrace.Propagate(err, "failed to head cos object")
+	}
+	return ObjectMeta{Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}, nil
+}
+
+func (b *CosBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.Object.Delete(ctx, key)
+	if err != nil && !isCosNotFound(err) {
+		return stacktrace.Propagate(err, "failed to delete cos object")
+	}
+	return nil
+}
+
+func (b *CosBackend) PresignPut(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return b.presign(ctx, key, ttl, http.MethodPut)
+}
+
+func (b *CosBackend) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return b.presign(ctx, key, ttl, http.MethodGet)
+}
+
+func (b *CosBackend) presign(ctx context.Context, key string, ttl time.Duration, method string) (string, error) {
+	presignedURL, err := b.client.Object.GetPresignedURL(ctx, method, key, b.client.GetCredential().SecretID, b.client.GetCredential().SecretKey, ttl, nil)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to presign cos url")
+	}
+	return presignedURL.String(), nil
+}
+
+func isCosNotFound(err error) bool {
+	cosErr, ok := err.(*cos.ErrorResponse)
+	return ok && cosErr.Response != nil && cosErr.Response.StatusCode == http.StatusNotFound
+}
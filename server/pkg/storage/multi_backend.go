@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ente-io/museum/pkg/repo"
+	"github.com/ente-io/stacktrace"
+	log "github.com/sirupsen/logrus"
+)
+
+// MultiBackend routes object operations to one of several Backends keyed by
+// storage class (e.g. "hot", "cold", "regional-eu"), so a museum instance
+// can tier collections across storage without every caller needing to know
+// which concrete backend a given collection lives on.
+type MultiBackend struct {
+	backends     map[string]Backend
+	defaultClass string
+}
+
+// NewMultiBackend builds a router over backends, keyed by storage class.
+// defaultClass must be a key in backends; it's used for collections with no
+// explicit storage class.
+func NewMultiBackend(backends map[string]Backend, defaultClass string) (*MultiBackend, error) {
+	if _, ok := backends[defaultClass]; !ok {
+		return nil, stacktrace.Propagate(fmt.Errorf("default storage class %q has no backend", defaultClass), "")
+	}
+	return &MultiBackend{backends: backends, defaultClass: defaultClass}, nil
+}
+
+// Resolve returns the Backend for storageClass, falling back to the default
+// backend when storageClass is empty.
+func (m *MultiBackend) Resolve(storageClass string) (Backend, error) {
+	if storageClass == "" {
+		storageClass = m.defaultClass
+	}
+	backend, ok := m.backends[storageClass]
+	if !ok {
+		return nil, stacktrace.Propagate(fmt.Errorf("no backend configured for storage class %q", storageClass), "")
+	}
+	return backend, nil
+}
+
+// AllowedStorageClasses returns the set of storage classes this instance has
+// a backend for, so callers can validate a requested class against it.
+func (m *MultiBackend) AllowedStorageClasses() []string {
+	classes := make([]string, 0, len(m.backends))
+	for class := range m.backends {
+		classes = append(classes, class)
+	}
+	return classes
+}
+
+// relocationLeaseTTL bounds how long a single RelocateCollection run may go
+// between progress checkpoints before its lease must be refreshed.
+const relocationLeaseTTL = 2 * time.Minute
+
+// RelocateCollection streams every object under objectKeys from fromClass to
+// toClass, persisting progress via leaseRepo so a crashed migration resumes
+// from the last completed key instead of restarting, and so a concurrent
+// relocation of the same collection between the same two storage classes is
+// rejected rather than racing this one. The lease is keyed by collection and
+// class pair, so relocations of one collection to different classes do not
+// exclude each other.
+func (m *MultiBackend) RelocateCollection(ctx context.Context, leaseRepo *repo.TaskLockRepository, collectionID int64, objectKeys []string, fromClass string, toClass string) (err error) {
+	from, err := m.Resolve(fromClass)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to resolve source backend")
+	}
+	to, err := m.Resolve(toClass)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to resolve destination backend")
+	}
+
+	leaseKey := fmt.Sprintf("relocate:%d:%s:%s", collectionID, fromClass, toClass)
+	resumeFrom := resumeIndexFromMetadata(ctx, leaseRepo, leaseKey, len(objectKeys))
+	lease, err := leaseRepo.AcquireLease(ctx, leaseKey, relocationLeaseTTL, strconv.Itoa(resumeFrom))
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to acquire relocation lease")
+	}
+	// On success the relocation is done and the checkpoint is no longer
+	// needed, so Release deletes it. On any other return path -- an error
+	// mid-loop or the lease being lost -- Abandon leaves the checkpoint
+	// Refresh already persisted in place for the next run to resume from.
+	succeeded := false
+	defer func() {
+		if succeeded {
+			lease.Release()
+		} else {
+			lease.Abandon()
+		}
+	}()
+	ctx = lease.Context
+
+	for i := resumeFrom; i < len(objectKeys); i++ {
+		key := objectKeys[i]
+		if ctx.Err() != nil {
+			return stacktrace.Propagate(ctx.Err(), "relocation aborted: lease lost")
+		}
+		if err := relocateObject(ctx, from, to, key); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("failed to relocate object %s", key))
+		}
+		// Persisted so a crash between here and the next iteration resumes
+		// at i+1 instead of re-relocating (and re-deleting the source of)
+		// objects already moved.
+		lease.Refresh(strconv.Itoa(i + 1))
+		log.WithFields(log.Fields{
+			"collection_id": collectionID,
+			"progress":      fmt.Sprintf("%d/%d", i+1, len(objectKeys)),
+		}).Info("relocated object")
+	}
+	succeeded = true
+	return nil
+}
+
+// resumeIndexFromMetadata recovers the progress checkpoint a prior,
+// crashed-or-preempted RelocateCollection run left behind, so this run
+// picks up where that one left off instead of re-relocating (and
+// re-deleting the source of) objects already moved. Any problem reading or
+// parsing it is treated as "start from scratch" rather than failing the
+// whole relocation.
+func resumeIndexFromMetadata(ctx context.Context, leaseRepo *repo.TaskLockRepository, leaseKey string, total int) int {
+	metadata, err := leaseRepo.PeekLeaseMetadata(ctx, leaseKey)
+	if err != nil || metadata == "" {
+		return 0
+	}
+	resumeFrom, err := strconv.Atoi(metadata)
+	if err != nil || resumeFrom < 0 || resumeFrom > total {
+		return 0
+	}
+	return resumeFrom
+}
+
+func relocateObject(ctx context.Context, from Backend, to Backend, key string) error {
+	body, meta, err := from.Get(ctx, key)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to read source object")
+	}
+	defer body.Close() // nolint: errcheck
+
+	if err := to.Put(ctx, key, body, meta.Size, meta.ContentType); err != nil {
+		return stacktrace.Propagate(err, "failed to write destination object")
+	}
+	if err := from.Delete(ctx, key); err != nil {
+		return stacktrace.Propagate(err, "failed to delete source object after relocation")
+	}
+	return nil
+}
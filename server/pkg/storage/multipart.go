@@ -0,0 +1,49 @@
+package storage
+
+import "context"
+
+// UploadedPart identifies one already-uploaded part of a multipart upload,
+// as returned by UploadPart and required, in order, by
+// CompleteMultipartUpload.
+type UploadedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// PartInfo describes one part already landed against an in-progress
+// multipart upload, as returned by ListParts -- enough for a client to
+// diff against the parts it thinks it sent and resume from the first gap
+// instead of re-uploading everything.
+type PartInfo struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+// MultipartBackend is implemented by backends that support resumable
+// multipart uploads (S3 and anything S3-compatible, via S3Backend). A
+// caller uploading a large object should type-assert for it and fall back
+// to a single Put against a Backend that doesn't support it.
+type MultipartBackend interface {
+	// CreateMultipartUpload starts a new multipart upload for key and
+	// returns the upload ID every subsequent part/complete/abort call must
+	// be made with.
+	CreateMultipartUpload(ctx context.Context, key string) (uploadID string, err error)
+	// UploadPart uploads a single part of an in-progress multipart upload
+	// and returns its ETag, needed (alongside partNumber) to later
+	// complete the upload.
+	UploadPart(ctx context.Context, key string, uploadID string, partNumber int, body []byte) (etag string, err error)
+	// CompleteMultipartUpload finalizes uploadID, assembling parts in the
+	// order given -- which need not match upload order, since a client may
+	// retry a part out of sequence.
+	CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []UploadedPart) error
+	// AbortMultipartUpload cancels uploadID and releases any storage its
+	// uploaded-so-far parts were holding. It is not an error for uploadID
+	// to already be complete or aborted.
+	AbortMultipartUpload(ctx context.Context, key string, uploadID string) error
+	// ListParts returns every part the backend has already received for
+	// uploadID, ordered by part number, so a client that lost track of its
+	// own progress (or never persisted it) can resume from the first part
+	// it's actually missing instead of restarting the whole upload.
+	ListParts(ctx context.Context, key string, uploadID string) ([]PartInfo, error)
+}
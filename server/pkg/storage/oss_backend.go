@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/ente-io/stacktrace"
+)
+
+// OssBackend stores objects in a single Alibaba Cloud Object Storage
+// Service (OSS) bucket. The underlying SDK predates context.Context, so ctx
+// is accepted for interface parity with every other Backend but isn't
+// threaded into the SDK calls themselves.
+type OssBackend struct {
+	bucket *oss.Bucket
+}
+
+// NewOssBackend builds a Backend backed by an Alibaba OSS bucket.
+func NewOssBackend(endpoint string, accessKeyID string, accessKeySecret string, bucketName string) (*OssBackend, error) {
+	client, err := oss.New(endpoint, accessKeyID, accessKeySecret)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create oss client")
+	}
+	bucket, err := client.Bucket(bucketName)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to open oss bucket")
+	}
+	return &OssBackend{bucket: bucket}, nil
+}
+
+func (b *OssBackend) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	err := b.bucket.PutObject(key, body, oss.ContentLength(size), oss.ContentType(contentType))
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to put oss object")
+	}
+	return nil
+}
+
+func (b *OssBackend) Get(ctx context.Context, key string) (io.ReadCloser, ObjectMeta, error) {
+	meta, err := b.Head(ctx, key)
+	if err != nil {
+		return nil, ObjectMeta{}, stacktrace.Propagate(err, "failed to head oss object before get")
+	}
+	body, err := b.bucket.GetObject(key)
+	if err != nil {
+		if isOssNotFound(err) {
+			return nil, ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		return nil, ObjectMeta{}, stacktrace.Propagate(err, "failed to get oss object")
+	}
+	return body, meta, nil
+}
+
+func (b *OssBackend) Head(ctx context.Context, key string) (ObjectMeta, error) {
+	headers, err := b.bucket.GetObjectDetailedMeta(key)
+	if err != nil {
+		if isOssNotFound(err) {
+			return ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		return ObjectMeta{}, stacktrace.Propagate(err, "failed to head oss object")
+	}
+	meta := ObjectMeta{ContentType: headers.Get("Content-Type")}
+	if _, scanErr := fmt.Sscanf(headers.Get("Content-Length"), "%d", &meta.Size); scanErr != nil {
+		return ObjectMeta{}, stacktrace.Propagate(scanErr, "failed to parse oss content length")
+	}
+	return meta, nil
+}
+
+func (b *OssBackend) Delete(ctx context.Context, key string) error {
+	if err := b.bucket.DeleteObject(key); err != nil && !isOssNotFound(err) {
+		return stacktrace.Propagate(err, "failed to delete oss object")
+	}
+	return nil
+}
+
+func (b *OssBackend) PresignPut(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	url, err := b.bucket.SignURL(key, oss.HTTPPut, int64(ttl.Seconds()))
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to presign oss put url")
+	}
+	return url, nil
+}
+
+func (b *OssBackend) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	url, err := b.bucket.SignURL(key, oss.HTTPGet, int64(ttl.Seconds()))
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to presign oss get url")
+	}
+	return url, nil
+}
+
+func isOssNotFound(err error) bool {
+	ossErr, ok := err.(oss.ServiceError)
+	return ok && ossErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/ente-io/stacktrace"
+)
+
+func credentialsFromStatic(accessKeyID string, secretAccessKey string) *credentials.Credentials {
+	return credentials.NewStaticCredentials(accessKeyID, secretAccessKey, "")
+}
+
+// S3Backend stores objects in a single S3-compatible bucket. It also backs
+// MinIOBackend, since MinIO speaks the S3 API.
+type S3Backend struct {
+	client      *s3.S3
+	bucket      string
+	sseKMSKeyID string
+}
+
+// S3BackendOption configures optional S3Backend behavior at construction
+// time, following the functional-options idiom so new knobs don't keep
+// widening NewS3Backend's signature.
+type S3BackendOption func(*S3Backend)
+
+// WithSSEKMSKeyID has every Put and multipart upload transparently
+// encrypted with the given KMS key, without the caller needing to know
+// about it per-request. It's meant for operators who want at-rest
+// encryption on a shared bucket; it's independent of (and overridden by,
+// per-object) the SSE-C path a caller can opt into explicitly via
+// PutWithSSEC/CreateMultipartUploadWithSSEC.
+func WithSSEKMSKeyID(keyID string) S3BackendOption {
+	return func(b *S3Backend) { b.sseKMSKeyID = keyID }
+}
+
+// NewS3Backend builds a Backend backed by AWS S3 (or any endpoint session
+// already carries, e.g. an S3-compatible regional provider).
+func NewS3Backend(sess *session.Session, bucket string, opts ...S3BackendOption) *S3Backend {
+	b := &S3Backend{client: s3.New(sess), bucket: bucket}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// NewMinIOBackend builds a Backend backed by a self-hosted MinIO instance.
+// MinIO is API-compatible with S3, so this is an S3Backend whose session is
+// pointed at a custom endpoint with path-style addressing, which MinIO
+// requires since it doesn't support virtual-hosted bucket DNS.
+func NewMinIOBackend(endpoint string, accessKeyID string, secretAccessKey string, region string, bucket string, opts ...S3BackendOption) (*S3Backend, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(endpoint),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentialsFromStatic(accessKeyID, secretAccessKey),
+	})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create MinIO session")
+	}
+	return NewS3Backend(sess, bucket, opts...), nil
+}
+
+func (b *S3Backend) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	uploader := s3manager.NewUploaderWithClient(b.client)
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	}
+	b.applySSEKMS(input)
+	_, err := uploader.UploadWithContext(ctx, input)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to put object")
+	}
+	return nil
+}
+
+// applySSEKMS sets SSE-KMS fields on a PutObject/multipart input when the
+// backend was constructed with WithSSEKMSKeyID, so every object lands
+// encrypted under that key without every caller having to ask for it.
+func (b *S3Backend) applySSEKMS(input *s3manager.UploadInput) {
+	if b.sseKMSKeyID == "" {
+		return
+	}
+	input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+	input.SSEKMSKeyId = aws.String(b.sseKMSKeyID)
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string) (io.ReadCloser, ObjectMeta, error) {
+	output, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		return nil, ObjectMeta{}, stacktrace.Propagate(err, "failed to get object")
+	}
+	meta := ObjectMeta{}
+	if output.ContentLength != nil {
+		meta.Size = *output.ContentLength
+	}
+	if output.ContentType != nil {
+		meta.ContentType = *output.ContentType
+	}
+	return output.Body, meta, nil
+}
+
+func (b *S3Backend) Head(ctx context.Context, key string) (ObjectMeta, error) {
+	output, err := b.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		return ObjectMeta{}, stacktrace.Propagate(err, "failed to head object")
+	}
+	meta := ObjectMeta{}
+	if output.ContentLength != nil {
+		meta.Size = *output.ContentLength
+	}
+	if output.ContentType != nil {
+		meta.ContentType = *output.ContentType
+	}
+	return meta, nil
+}
+
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to delete object")
+	}
+	return nil
+}
+
+// s3DeleteBatchLimit is the maximum number of keys S3's DeleteObjects
+// accepts in a single request.
+const s3DeleteBatchLimit = 1000
+
+// DeleteBatch implements storage.BatchDeleter, removing up to
+// s3DeleteBatchLimit keys in a single request instead of one DeleteObject
+// call per key.
+func (b *S3Backend) DeleteBatch(ctx context.Context, keys []string) ([]string, error) {
+	var failedKeys []string
+	for start := 0; start < len(keys); start += s3DeleteBatchLimit {
+		end := start + s3DeleteBatchLimit
+		if end > len(keys) {
+			end = len(keys)
+		}
+		objects := make([]*s3.ObjectIdentifier, end-start)
+		for i, key := range keys[start:end] {
+			objects[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+		}
+		output, err := b.client.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(b.bucket),
+			Delete: &s3.Delete{Objects: objects},
+		})
+		if err != nil {
+			return failedKeys, stacktrace.Propagate(err, "failed to batch delete objects")
+		}
+		// A 200 OK here doesn't mean every key was deleted -- S3 reports
+		// per-key failures (e.g. denied by a bucket policy) in the
+		// response body instead of failing the request.
+		for _, objErr := range output.Errors {
+			if objErr.Key != nil {
+				failedKeys = append(failedKeys, *objErr.Key)
+			}
+		}
+	}
+	return failedKeys, nil
+}
+
+func (b *S3Backend) PresignPut(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	req, _ := b.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	req.SetContext(ctx)
+	url, err := req.Presign(ttl)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to presign put")
+	}
+	return url, nil
+}
+
+func (b *S3Backend) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	req, _ := b.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	req.SetContext(ctx)
+	url, err := req.Presign(ttl)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to presign get")
+	}
+	return url, nil
+}
+
+func isNotFound(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case s3.ErrCodeNoSuchKey, "NotFound":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ente-io/stacktrace"
+)
+
+func (b *S3Backend) CreateMultipartUpload(ctx context.Context, key string) (string, error) {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	}
+	if b.sseKMSKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(b.sseKMSKeyID)
+	}
+	output, err := b.client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to create multipart upload")
+	}
+	return *output.UploadId, nil
+}
+
+// CreateMultipartUploadWithSSEC implements storage.SSECMultipartBackend.
+// The same key must be passed again to every UploadPartWithSSEC call for
+// this upload.
+func (b *S3Backend) CreateMultipartUploadWithSSEC(ctx context.Context, key string, sseKey SSECustomerKey) (string, error) {
+	output, err := b.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(b.bucket),
+		Key:                  aws.String(key),
+		SSECustomerAlgorithm: aws.String(sseCustomerAlgorithm),
+		SSECustomerKey:       aws.String(base64.StdEncoding.EncodeToString(sseKey.Key)),
+		SSECustomerKeyMD5:    aws.String(sseKey.KeyMD5),
+	})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to create multipart upload with sse-c")
+	}
+	return *output.UploadId, nil
+}
+
+func (b *S3Backend) UploadPart(ctx context.Context, key string, uploadID string, partNumber int, body []byte) (string, error) {
+	output, err := b.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(b.bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadID),
+		PartNumber: aws.Int64(int64(partNumber)),
+		Body:       bytes.NewReader(body),
+	})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to upload part")
+	}
+	return *output.ETag, nil
+}
+
+// UploadPartWithSSEC implements storage.SSECMultipartBackend, passing the
+// same key the upload was created with -- S3 rejects a part whose key
+// doesn't match the one given to CreateMultipartUploadWithSSEC.
+func (b *S3Backend) UploadPartWithSSEC(ctx context.Context, key string, uploadID string, partNumber int, body []byte, sseKey SSECustomerKey) (string, error) {
+	output, err := b.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:               aws.String(b.bucket),
+		Key:                  aws.String(key),
+		UploadId:             aws.String(uploadID),
+		PartNumber:           aws.Int64(int64(partNumber)),
+		Body:                 bytes.NewReader(body),
+		SSECustomerAlgorithm: aws.String(sseCustomerAlgorithm),
+		SSECustomerKey:       aws.String(base64.StdEncoding.EncodeToString(sseKey.Key)),
+		SSECustomerKeyMD5:    aws.String(sseKey.KeyMD5),
+	})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to upload part with sse-c")
+	}
+	return *output.ETag, nil
+}
+
+func (b *S3Backend) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []UploadedPart) error {
+	completedParts := make([]*s3.CompletedPart, len(parts))
+	for i, part := range parts {
+		completedParts[i] = &s3.CompletedPart{
+			PartNumber: aws.Int64(int64(part.PartNumber)),
+			ETag:       aws.String(part.ETag),
+		}
+	}
+	_, err := b.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(b.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to complete multipart upload")
+	}
+	return nil
+}
+
+// s3ListPartsPageSize is the maximum number of parts S3's ListParts
+// returns per page; an upload with more parts than this needs more than
+// one request to enumerate in full.
+const s3ListPartsPageSize = 1000
+
+// ListParts implements storage.MultipartBackend, paging through every part
+// S3 has already received for uploadID.
+func (b *S3Backend) ListParts(ctx context.Context, key string, uploadID string) ([]PartInfo, error) {
+	var parts []PartInfo
+	var partNumberMarker *string
+	for {
+		output, err := b.client.ListPartsWithContext(ctx, &s3.ListPartsInput{
+			Bucket:           aws.String(b.bucket),
+			Key:              aws.String(key),
+			UploadId:         aws.String(uploadID),
+			MaxParts:         aws.Int64(s3ListPartsPageSize),
+			PartNumberMarker: partNumberMarker,
+		})
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "failed to list parts")
+		}
+		for _, part := range output.Parts {
+			info := PartInfo{PartNumber: int(*part.PartNumber), ETag: *part.ETag}
+			if part.Size != nil {
+				info.Size = *part.Size
+			}
+			parts = append(parts, info)
+		}
+		if output.IsTruncated == nil || !*output.IsTruncated {
+			break
+		}
+		partNumberMarker = output.NextPartNumberMarker
+	}
+	return parts, nil
+}
+
+func (b *S3Backend) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	_, err := b.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(b.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchUpload {
+		// Already completed or aborted.
+		return nil
+	}
+	if err != nil && !isNotFound(err) {
+		return stacktrace.Propagate(err, "failed to abort multipart upload")
+	}
+	return nil
+}
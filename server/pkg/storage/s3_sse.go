@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/ente-io/stacktrace"
+)
+
+// sseCustomerAlgorithm is the only algorithm S3 supports for SSE-C.
+const sseCustomerAlgorithm = "AES256"
+
+func (b *S3Backend) PutWithSSEC(ctx context.Context, key string, body io.Reader, size int64, contentType string, sseKey SSECustomerKey) error {
+	uploader := s3manager.NewUploaderWithClient(b.client)
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	}
+	applySSEC(input, sseKey)
+	_, err := uploader.UploadWithContext(ctx, input)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to put object with sse-c")
+	}
+	return nil
+}
+
+func (b *S3Backend) GetWithSSEC(ctx context.Context, key string, sseKey SSECustomerKey) (io.ReadCloser, ObjectMeta, error) {
+	output, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket:               aws.String(b.bucket),
+		Key:                  aws.String(key),
+		SSECustomerAlgorithm: aws.String(sseCustomerAlgorithm),
+		SSECustomerKey:       aws.String(base64.StdEncoding.EncodeToString(sseKey.Key)),
+		SSECustomerKeyMD5:    aws.String(sseKey.KeyMD5),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		if isSSECKeyMismatch(err) {
+			return nil, ObjectMeta{}, stacktrace.Propagate(ErrSSECKeyMismatch, "")
+		}
+		return nil, ObjectMeta{}, stacktrace.Propagate(err, "failed to get object with sse-c")
+	}
+	meta := ObjectMeta{}
+	if output.ContentLength != nil {
+		meta.Size = *output.ContentLength
+	}
+	if output.ContentType != nil {
+		meta.ContentType = *output.ContentType
+	}
+	return output.Body, meta, nil
+}
+
+func (b *S3Backend) HeadWithSSEC(ctx context.Context, key string, sseKey SSECustomerKey) (ObjectMeta, error) {
+	output, err := b.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket:               aws.String(b.bucket),
+		Key:                  aws.String(key),
+		SSECustomerAlgorithm: aws.String(sseCustomerAlgorithm),
+		SSECustomerKey:       aws.String(base64.StdEncoding.EncodeToString(sseKey.Key)),
+		SSECustomerKeyMD5:    aws.String(sseKey.KeyMD5),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ObjectMeta{}, stacktrace.Propagate(ErrObjectNotFound, "")
+		}
+		if isSSECKeyMismatch(err) {
+			return ObjectMeta{}, stacktrace.Propagate(ErrSSECKeyMismatch, "")
+		}
+		return ObjectMeta{}, stacktrace.Propagate(err, "failed to head object with sse-c")
+	}
+	meta := ObjectMeta{}
+	if output.ContentLength != nil {
+		meta.Size = *output.ContentLength
+	}
+	if output.ContentType != nil {
+		meta.ContentType = *output.ContentType
+	}
+	return meta, nil
+}
+
+// applySSEC sets SSE-C fields on a PutObject/multipart input from sseKey.
+func applySSEC(input *s3manager.UploadInput, sseKey SSECustomerKey) {
+	input.SSECustomerAlgorithm = aws.String(sseCustomerAlgorithm)
+	input.SSECustomerKey = aws.String(base64.StdEncoding.EncodeToString(sseKey.Key))
+	input.SSECustomerKeyMD5 = aws.String(sseKey.KeyMD5)
+}
+
+// isSSECKeyMismatch reports whether err is S3 rejecting a request because
+// the supplied SSE-C key doesn't match the one the object was encrypted
+// with. S3 returns this as a 400 Bad Request, distinct from the 403s it
+// uses for authorization failures.
+func isSSECKeyMismatch(err error) bool {
+	reqErr, ok := err.(awserr.RequestFailure)
+	return ok && reqErr.StatusCode() == 400
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrSSECKeyMismatch is returned by a SSECBackend's Get/HeadWithSSEC when
+// the supplied customer key doesn't match the one the object was
+// originally encrypted with. The object store itself rejects the request
+// before returning any ciphertext, so the caller never learns anything
+// beyond "wrong key".
+var ErrSSECKeyMismatch = errors.New("sse-c key does not match object")
+
+// SSECustomerKey is a customer-provided encryption key (SSE-C), passed
+// through to a request's SSECustomerKey/SSECustomerKeyMD5/
+// SSECustomerAlgorithm fields instead of relying on the bucket's
+// server-side default. Key is the raw 32-byte key; KeyMD5 is its base64
+// MD5, used by the store to detect transport corruption.
+type SSECustomerKey struct {
+	Key    []byte
+	KeyMD5 string
+}
+
+// SSECBackend is implemented by backends that support per-object
+// customer-provided encryption keys (S3 and anything S3-compatible, via
+// S3Backend). It's a defense-in-depth layer on top of the client-side
+// encryption Ente already assumes -- an operator running museum against a
+// shared object store can ask for SSE-C without either museum or the
+// object store ever seeing plaintext key material it didn't already have.
+type SSECBackend interface {
+	PutWithSSEC(ctx context.Context, key string, body io.Reader, size int64, contentType string, sseKey SSECustomerKey) error
+	GetWithSSEC(ctx context.Context, key string, sseKey SSECustomerKey) (io.ReadCloser, ObjectMeta, error)
+	HeadWithSSEC(ctx context.Context, key string, sseKey SSECustomerKey) (ObjectMeta, error)
+}
+
+// SSECMultipartBackend extends MultipartBackend for backends that can also
+// apply a customer-provided key across a multipart upload. The key must be
+// supplied identically on CreateMultipartUpload and every UploadPart call;
+// CompleteMultipartUpload and AbortMultipartUpload need no key since they
+// don't touch object bytes.
+type SSECMultipartBackend interface {
+	CreateMultipartUploadWithSSEC(ctx context.Context, key string, sseKey SSECustomerKey) (uploadID string, err error)
+	UploadPartWithSSEC(ctx context.Context, key string, uploadID string, partNumber int, body []byte, sseKey SSECustomerKey) (etag string, err error)
+}
@@ -0,0 +1,36 @@
+// Package blurhash computes the compact blurhash string museum stores
+// alongside a file's public magic metadata, so clients can render a preview
+// before deciding whether a full file (or even its thumbnail) is worth
+// downloading.
+package blurhash
+
+import (
+	"bytes"
+	"image"
+	_ "image/jpeg"
+
+	"github.com/buckket/go-blurhash"
+	"github.com/ente-io/stacktrace"
+)
+
+// xComponents and yComponents control how much detail the hash captures;
+// 4x3 is the common default for small thumbnail-sized previews.
+const (
+	xComponents = 4
+	yComponents = 3
+)
+
+// Encode computes the blurhash for a decrypted thumbnail image, to be
+// stored as part of a file's public magic metadata at upload time.
+func Encode(decryptedThumbnail []byte) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(decryptedThumbnail))
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to decode thumbnail")
+	}
+
+	hash, err := blurhash.Encode(xComponents, yComponents, img)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to encode blurhash")
+	}
+	return hash, nil
+}
@@ -0,0 +1,38 @@
+// Package tracing wires a shared OpenTelemetry tracer into request-handling
+// code that isn't already covered by the gin/otel HTTP middleware, such as
+// controller methods that fan out into multiple repository calls.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this package's spans in trace backends.
+const instrumentationName = "github.com/ente-io/museum"
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartSpan starts a child span named name under ctx with the given
+// attributes, to be closed by the caller via defer span.End().
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError sets the span's status to an error code and records err's
+// message, matching the propagated stacktrace error this codebase already
+// surfaces to callers. A nil err is a no-op so this can be deferred
+// unconditionally.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}